@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/velovix/TISC-100/tis"
+)
+
+const debugHelp = `Commands:
+  step, s [n]       run n cycles, or one if n isn't given
+  next, n [x-y]     run until node x-y finishes an instruction, or any node
+                    if no node is given
+  continue, c       run until a breakpoint is reached or the machine stops
+  break, b x-y line pause before the instruction on the given line of node x-y
+  clear x-y line    remove the breakpoint from the given line of node x-y
+  print, p          print the state of every node
+  quit, q           stop debugging
+  help, h           print this message
+
+An empty line repeats the last command. Console input is read from the same
+place as commands whenever an input needs its next number. Press Ctrl-C to pause
+a running machine.`
+
+// debugger is an interactive front end that runs a machine a little at a time
+// and shows what's inside of it.
+type debugger struct {
+	mach *tis.Machine
+	in   *bufio.Reader
+	out  io.Writer
+
+	stalled bool           // Whether the last cycle made no progress
+	paused  map[string]int // Instructions each node had finished when it last paused on a breakpoint
+}
+
+// newDebugger creates a new debugger for the given machine that reads
+// commands from in and writes to out.
+func newDebugger(mach *tis.Machine, in *bufio.Reader, out io.Writer) *debugger {
+	return &debugger{
+		mach:   mach,
+		in:     in,
+		out:    out,
+		paused: make(map[string]int)}
+}
+
+// run reads and runs commands until the user quits or the input ends.
+func (d *debugger) run() {
+	fmt.Fprintln(d.out, "Type 'help' for a list of commands.")
+
+	var last []string
+	for {
+		fmt.Fprint(d.out, "(tis) ")
+		line, err := d.in.ReadString('\n')
+		if err != nil && line == "" {
+			fmt.Fprintln(d.out)
+			return
+		}
+
+		// Repeat the last command on an empty line
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			args = last
+		}
+		if len(args) == 0 {
+			continue
+		}
+		last = args
+
+		switch args[0] {
+		case "step", "s":
+			n := 1
+			if len(args) > 1 {
+				if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+					fmt.Fprintln(d.out, "Invalid cycle count '"+args[1]+"'")
+					continue
+				}
+			}
+			for i := 0; i < n; i++ {
+				if d.cycle() != nil || d.stalled {
+					break
+				}
+			}
+			d.print()
+		case "next", "n":
+			var target string
+			if len(args) > 1 {
+				if !d.isExecutionNode(args[1]) {
+					continue
+				}
+				target = args[1]
+			}
+			d.runUntil(target, true)
+			d.print()
+		case "continue", "c":
+			d.runUntil("", false)
+			d.print()
+		case "break", "b":
+			d.setBreakpoint(args[1:], true)
+		case "clear":
+			d.setBreakpoint(args[1:], false)
+		case "print", "p":
+			d.print()
+		case "quit", "q":
+			return
+		case "help", "h":
+			fmt.Fprintln(d.out, debugHelp)
+		default:
+			fmt.Fprintln(d.out, "Unknown command '"+args[0]+"'. Type 'help' for a list of commands.")
+		}
+	}
+}
+
+// cycle runs the machine for one cycle. If a node is on a breakpoint it hasn't
+// paused on yet, the machine is paused before the cycle instead, and that node
+// is returned. The cycle that resumes from a breakpoint runs past it. A node
+// that lands on a breakpoint during the cycle is returned too.
+func (d *debugger) cycle() *tis.NodeState {
+	if en := d.breakpoint(); en != nil {
+		return en
+	}
+
+	d.stalled = !d.mach.Step()
+	if d.stalled {
+		if err := d.mach.Halted(); err != nil {
+			fmt.Fprintln(d.out, err)
+		} else if err := d.mach.Deadlock(); err != nil {
+			fmt.Fprintln(d.out, err)
+		} else {
+			fmt.Fprintln(d.out, "The machine can't make any more progress")
+		}
+	}
+
+	return d.breakpoint()
+}
+
+// breakpoint returns the first node that is on a breakpoint it hasn't paused
+// on yet, and remembers that it paused there. A node pauses on a breakpoint
+// again once it has finished another instruction and come back to it.
+func (d *debugger) breakpoint() *tis.NodeState {
+	for _, en := range d.executionNodes() {
+		if !en.Breakpoint {
+			continue
+		}
+		if executed, ok := d.paused[en.Name]; ok && executed == en.Executed {
+			continue
+		}
+
+		d.paused[en.Name] = en.Executed
+		fmt.Fprintln(d.out, "Node", en.Name, "reached a breakpoint on line", en.Line)
+		return &en
+	}
+
+	return nil
+}
+
+// runUntil runs the machine until a breakpoint is reached, the machine stalls
+// or the user interrupts it. If next is true, it also stops once the given node
+// finishes an instruction, or once any node does if no node is given.
+func (d *debugger) runUntil(target string, next bool) {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	for {
+		before := d.executed(target)
+		if d.cycle() != nil || d.stalled {
+			return
+		}
+		if next && d.executed(target) != before {
+			return
+		}
+
+		select {
+		case <-interrupt:
+			fmt.Fprintln(d.out, "Paused")
+			return
+		default:
+		}
+	}
+}
+
+// setBreakpoint sets or clears the breakpoint on the instruction at the
+// node and line given in args.
+func (d *debugger) setBreakpoint(args []string, set bool) {
+	if len(args) != 2 {
+		fmt.Fprintln(d.out, "Expected a node and a line number")
+		return
+	}
+
+	if !d.isExecutionNode(args[0]) {
+		return
+	}
+
+	line, err := strconv.Atoi(args[1])
+	if err != nil {
+		fmt.Fprintln(d.out, "Invalid line number '"+args[1]+"'")
+		return
+	}
+
+	line, err = d.mach.SetBreakpoint(args[0], line, set)
+	if err != nil {
+		fmt.Fprintln(d.out, "Node", args[0], "has no instructions on or after line", args[1])
+		return
+	}
+	if set {
+		fmt.Fprintln(d.out, "Breakpoint set on node", args[0], "line", line)
+	} else {
+		fmt.Fprintln(d.out, "Breakpoint cleared on node", args[0], "line", line)
+	}
+}
+
+// print writes the state of every node in the machine.
+func (d *debugger) print() {
+	fmt.Fprintln(d.out, "Cycle", d.mach.Cycle())
+
+	w := tabwriter.NewWriter(d.out, 0, 8, 2, ' ', 0)
+	for _, n := range d.mach.Nodes() {
+		switch n.Kind {
+		case tis.ExecutionNode:
+			if n.Instructions == 0 {
+				fmt.Fprintf(w, "%v\tempty\n", n.Name)
+				continue
+			}
+
+			fmt.Fprintf(w, "%v\t%v\tline %v\t%v\tACC %v\tBAK %v", n.Name, n.Mode, n.Line, n.Instruction, n.ACC, n.BAK)
+			if n.Waiting != "" {
+				fmt.Fprint(w, "\t", n.Waiting)
+			}
+			fmt.Fprintln(w)
+		case tis.StackNode:
+			fmt.Fprintf(w, "%v\t%v\tstack\t%v\n", n.Name, n.Mode, n.Values)
+		}
+	}
+	w.Flush()
+}
+
+// executionNodes returns the state of every execution node in the machine
+// that has code.
+func (d *debugger) executionNodes() []tis.NodeState {
+	var nodes []tis.NodeState
+	for _, n := range d.mach.Nodes() {
+		if n.Kind == tis.ExecutionNode && n.Instructions > 0 {
+			nodes = append(nodes, n)
+		}
+	}
+
+	return nodes
+}
+
+// executed returns the number of instructions the named node has finished, or
+// the number finished across all nodes if no node is given.
+func (d *debugger) executed(name string) int {
+	count := 0
+	for _, en := range d.executionNodes() {
+		if name == "" || en.Name == name {
+			count += en.Executed
+		}
+	}
+
+	return count
+}
+
+// isExecutionNode returns true if there is an execution node with the given
+// name, or prints an error and returns false if there isn't one.
+func (d *debugger) isExecutionNode(name string) bool {
+	if n, ok := d.mach.Node(name); ok && n.Kind == tis.ExecutionNode {
+		return true
+	}
+
+	fmt.Fprintln(d.out, "No execution node named '"+name+"'")
+	return false
+}
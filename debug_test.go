@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/velovix/TISC-100/tis"
+)
+
+// debugSession runs the debugger on a machine with the given config and code,
+// feeding it the given commands, and returns everything it wrote. The console
+// input IN, if the config has one, reads the given numbers.
+func debugSession(t *testing.T, configJSON string, code map[string]string, input []tis.Number, commands string) string {
+	mach := newTestMachine(t, parseTestConfig(t, configJSON), code, consoleStreams{
+		inputs:  map[string]tis.InputStream{"IN": tis.NewSliceInput(input)},
+		outputs: map[string]tis.OutputStream{"OUT": &tis.SliceOutput{}}})
+
+	var out bytes.Buffer
+	newDebugger(mach, bufio.NewReader(strings.NewReader(commands)), &out).run()
+	return out.String()
+}
+
+// checkRows checks that each of the rows appears in the debugger's output in
+// order. Rows are compared field by field, so the columns the state is lined
+// up in don't matter.
+func checkRows(t *testing.T, out string, rows ...string) {
+	lines := strings.Split(out, "\n")
+	for _, row := range rows {
+		found := false
+		for len(lines) > 0 && !found {
+			found = strings.Join(strings.Fields(lines[0]), " ") == row
+			lines = lines[1:]
+		}
+		if !found {
+			t.Errorf("expected the output to contain %q in order, got:\n%v", row, out)
+			return
+		}
+	}
+}
+
+// TestDebuggerStep tests that stepping runs the given number of cycles and
+// prints the registers of each execution node and the contents of each stack
+// node, and that an empty line repeats the last command. Stepping stops early
+// once the machine can't make any more progress.
+func TestDebuggerStep(t *testing.T) {
+	out := debugSession(t, `{
+		"nodes": [["e", "s"]],
+		"consoleIn": {"side": "top", "pos": 0}}`,
+		map[string]string{"0-0": "mov up right\nmov 6 right\nadd 1\n"},
+		[]tis.Number{5},
+		"step 4\n\nprint\nquit\n")
+
+	checkRows(t, out,
+		"(tis) Cycle 4",
+		"0-0 RUN line 3 ADD 1 ACC 0 BAK 0",
+		"1-0 RUN stack [5 6]",
+		"(tis) The machine can't make any more progress",
+		"Cycle 6",
+		"0-0 READ line 1 MOV UP RIGHT ACC 1 BAK 0 waiting to read UP",
+		"(tis) Cycle 6",
+		"1-0 WRTE stack [5 6]")
+}
+
+// TestDebuggerNext tests that next runs until the given node, or any node,
+// finishes an instruction.
+func TestDebuggerNext(t *testing.T) {
+	out := debugSession(t, `{"nodes": [["e", "e"]]}`,
+		map[string]string{
+			"0-0": "mov 1 right\n",
+			"1-0": "mov left acc\nnop\n"},
+		nil,
+		"next 0-0\nnext\nquit\n")
+
+	// 1-0 reads the number 0-0 sent on the second cycle, which finishes both
+	// of their instructions, and then finishes a NOP on the third
+	checkRows(t, out,
+		"(tis) Cycle 2",
+		"0-0 RUN line 1 MOV 1 RIGHT ACC 0 BAK 0",
+		"1-0 RUN line 2 NOP ACC 1 BAK 0",
+		"(tis) Cycle 3",
+		"1-0 RUN line 1 MOV LEFT ACC ACC 1 BAK 0")
+}
+
+// TestDebuggerBreakpoints tests that the machine pauses before an instruction
+// with a breakpoint every time the node gets to it, until the breakpoint is
+// cleared.
+func TestDebuggerBreakpoints(t *testing.T) {
+	out := debugSession(t, `{"nodes": [["e"]]}`,
+		map[string]string{"0-0": "add 1\nadd 2\nadd 3\n"},
+		nil,
+		"break 0-0 3\ncontinue\ncontinue\nclear 0-0 3\nstep 3\nquit\n")
+
+	checkRows(t, out,
+		"(tis) Breakpoint set on node 0-0 line 3",
+		"(tis) Node 0-0 reached a breakpoint on line 3",
+		"Cycle 2",
+		"0-0 RUN line 3 ADD 3 ACC 3 BAK 0",
+		"(tis) Node 0-0 reached a breakpoint on line 3",
+		"Cycle 5",
+		"0-0 RUN line 3 ADD 3 ACC 9 BAK 0",
+		"(tis) Breakpoint cleared on node 0-0 line 3",
+		"(tis) Cycle 8",
+		"0-0 RUN line 3 ADD 3 ACC 15 BAK 0")
+}
+
+// TestDebuggerBreakpointFirstLine tests that a breakpoint on the instruction a
+// node starts on pauses the machine before it first runs, and that waiting on
+// a breakpoint's instruction after resuming doesn't pause the machine again.
+func TestDebuggerBreakpointFirstLine(t *testing.T) {
+	out := debugSession(t, `{"nodes": [["e", "e"]]}`,
+		map[string]string{
+			"0-0": "nop\nnop\nmov 1 right\n",
+			"1-0": "!mov left acc\n"},
+		nil,
+		"continue\ncontinue\nquit\n")
+
+	// 1-0 waits until the fourth cycle for the number 0-0 sends, and then
+	// comes back around to the breakpoint
+	checkRows(t, out,
+		"(tis) Node 1-0 reached a breakpoint on line 1",
+		"Cycle 0",
+		"1-0 RUN line 1 MOV LEFT ACC ACC 0 BAK 0",
+		"(tis) Node 1-0 reached a breakpoint on line 1",
+		"Cycle 4",
+		"1-0 RUN line 1 MOV LEFT ACC ACC 1 BAK 0")
+}
+
+// TestDebuggerStall tests that the debugger stops running the machine and says
+// why once it can't make any more progress.
+func TestDebuggerStall(t *testing.T) {
+	// Running out of input is how a program normally finishes
+	out := debugSession(t, `{
+		"nodes": [["e"]],
+		"consoleIn": {"side": "top", "pos": 0}}`,
+		map[string]string{"0-0": "add up\n"},
+		[]tis.Number{1, 2},
+		"continue\nquit\n")
+	checkRows(t, out,
+		"(tis) The machine can't make any more progress",
+		"Cycle 3",
+		"0-0 READ line 1 ADD UP ACC 3 BAK 0 waiting to read UP")
+
+	// Nodes waiting on each other are deadlocked
+	out = debugSession(t, `{"nodes": [["e", "e"]]}`,
+		map[string]string{
+			"0-0": "mov 1 right\n",
+			"1-0": "mov 2 left\n"},
+		nil,
+		"step 10\nquit\n")
+	checkRows(t, out,
+		"(tis) deadlock on cycle 2: no node can make progress",
+		"0-0 is waiting to write RIGHT on line 1: MOV 1 RIGHT",
+		"1-0 is waiting to write LEFT on line 1: MOV 2 LEFT",
+		"Cycle 2")
+}
+
+// TestDebuggerInvalidCommands tests that mistakes in commands are reported
+// without running the machine.
+func TestDebuggerInvalidCommands(t *testing.T) {
+	out := debugSession(t, `{"nodes": [["e"]]}`,
+		map[string]string{"0-0": "nop\n"},
+		nil,
+		"step x\nfoo\nnext 9-9\nbreak 0-0\nbreak 0-0 y\nbreak 0-0 5\nprint\n")
+
+	checkRows(t, out,
+		"(tis) Invalid cycle count 'x'",
+		"(tis) Unknown command 'foo'. Type 'help' for a list of commands.",
+		"(tis) No execution node named '9-9'",
+		"(tis) Expected a node and a line number",
+		"(tis) Invalid line number 'y'",
+		"(tis) Node 0-0 has no instructions on or after line 5",
+		"(tis) Cycle 0")
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/velovix/TISC-100/tis"
+)
+
+// driveTick is how often an interactive view runs the machine. A running
+// machine steps once per tick, and a fast-forwarding machine steps as many
+// times as it can.
+const driveTick = 50 * time.Millisecond
+
+// panelHistory is how many numbers are kept for each console stream shown in
+// an interactive view.
+const panelHistory = 100
+
+// panelStream sits in front of a console input or output and records the
+// numbers that move through it, so that an interactive view can show them.
+type panelStream struct {
+	name   string
+	input  bool
+	values []tis.Number // The most recent numbers, oldest first
+
+	in  tis.InputStream
+	out tis.OutputStream
+}
+
+// watchStreams puts a panelStream in front of each of the project's console
+// inputs and outputs, except for image outputs, which are drawn on their own.
+// The machine must be created with the streams afterwards.
+func watchStreams(config tis.Config, streams *consoleStreams) []*panelStream {
+	var panels []*panelStream
+	for _, sc := range config.Inputs {
+		ps := &panelStream{
+			name:  sc.Name,
+			input: true,
+			in:    streams.inputs[sc.Name]}
+		streams.inputs[sc.Name] = ps
+		panels = append(panels, ps)
+	}
+	for _, sc := range config.Outputs {
+		if sc.Type == "image" {
+			continue
+		}
+		ps := &panelStream{
+			name: sc.Name,
+			out:  streams.outputs[sc.Name]}
+		streams.outputs[sc.Name] = ps
+		panels = append(panels, ps)
+	}
+
+	return panels
+}
+
+// Next reads the next number from the input and records it.
+func (ps *panelStream) Next() (tis.Number, bool) {
+	n, ok := ps.in.Next()
+	if ok {
+		ps.record(n)
+	}
+
+	return n, ok
+}
+
+// Put records the number and writes it to the output.
+func (ps *panelStream) Put(n tis.Number) {
+	ps.record(n)
+	ps.out.Put(n)
+}
+
+func (ps *panelStream) record(n tis.Number) {
+	ps.values = append(ps.values, n)
+	if len(ps.values) > panelHistory {
+		ps.values = ps.values[len(ps.values)-panelHistory:]
+	}
+}
+
+// codeLines splits the code of each node into lines, leaving off any blank
+// lines at the end.
+func codeLines(code map[string]string) map[string][]string {
+	lines := make(map[string][]string)
+	for name, src := range code {
+		nodeLines := strings.Split(strings.Replace(src, "\r\n", "\n", -1), "\n")
+		for len(nodeLines) > 0 && strings.TrimSpace(nodeLines[len(nodeLines)-1]) == "" {
+			nodeLines = nodeLines[:len(nodeLines)-1]
+		}
+		lines[name] = nodeLines
+	}
+
+	return lines
+}
+
+// driver runs a machine for an interactive view. The machine starts off
+// paused, and can be stepped, run at a pace that can be followed or
+// fast-forwarded.
+type driver struct {
+	mach *tis.Machine
+
+	running bool
+	fast    bool
+	stopped string // Why the machine stopped, once it has
+}
+
+// run runs the machine one cycle per tick.
+func (d *driver) run() {
+	d.running = d.stopped == ""
+	d.fast = false
+}
+
+// fastForward runs the machine as fast as it can.
+func (d *driver) fastForward() {
+	d.fast = d.stopped == ""
+	d.running = false
+}
+
+// pause stops the machine from running on its own.
+func (d *driver) pause() {
+	d.running, d.fast = false, false
+}
+
+// stepOnce pauses the machine and runs it for one cycle.
+func (d *driver) stepOnce() {
+	d.pause()
+	d.step()
+}
+
+// tick runs the machine for one tick if it's running. It returns true if
+// anything changed.
+func (d *driver) tick() bool {
+	switch {
+	case d.fast:
+		// Leave some of the tick for showing the machine
+		deadline := time.Now().Add(driveTick * 4 / 5)
+		for time.Now().Before(deadline) && d.step() {
+		}
+		return true
+	case d.running:
+		d.step()
+		return true
+	}
+
+	return false
+}
+
+// step runs the machine for a cycle and returns true if it's still going.
+// Once the machine stops, the reason is kept to show to the user.
+func (d *driver) step() bool {
+	if d.stopped != "" {
+		return false
+	}
+	if d.mach.Step() {
+		return true
+	}
+
+	d.pause()
+	if err := d.mach.Halted(); err != nil {
+		d.stopped = "HALTED: " + err.Error()
+	} else if d.mach.Deadlock() != nil {
+		d.stopped = "DEADLOCKED"
+	} else {
+		d.stopped = "STOPPED"
+	}
+
+	return false
+}
+
+// state describes what the machine is doing.
+func (d *driver) state() string {
+	switch {
+	case d.stopped != "":
+		return d.stopped
+	case d.fast:
+		return "FAST-FORWARD"
+	case d.running:
+		return "RUNNING"
+	}
+
+	return "PAUSED"
+}
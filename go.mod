@@ -0,0 +1,3 @@
+module github.com/velovix/TISC-100
+
+go 1.16
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/velovix/TISC-100/tis"
+)
+
+// project is a machine config along with the directory that holds the code
+// for its nodes.
+type project struct {
+	dir    string
+	config tis.Config
+}
+
+// projectFlags are the command line flags that pick which project to use.
+type projectFlags struct {
+	dir    string
+	config string
+	strict bool
+}
+
+// register adds the project flags to the given flag set.
+func (pf *projectFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&pf.dir, "dir", ".", "look for the project's machine.json and .tis files in `DIR`")
+	fs.StringVar(&pf.config, "config", "", "read the machine config from `FILE` instead of DIR/machine.json")
+}
+
+// registerStrict adds the flag that holds code to the game's limits to the
+// given flag set. It is only registered by commands that run code.
+func (pf *projectFlags) registerStrict(fs *flag.FlagSet) {
+	fs.BoolVar(&pf.strict, "strict", false, "reject code over the game's limit of 15 lines of 18 characters, even if the config doesn't ask for it")
+}
+
+// open loads the project the flags point to.
+func (pf projectFlags) open() (project, error) {
+	p := project{
+		dir: pf.dir}
+
+	configFile := pf.config
+	if configFile == "" {
+		configFile = p.path("machine.json")
+	}
+
+	config, err := tis.LoadConfig(configFile)
+	if err != nil {
+		return project{}, err
+	}
+	p.config = config
+	if pf.strict {
+		p.config.Strict = true
+	}
+
+	return p, nil
+}
+
+// path returns the location of the given file in the project directory.
+func (p project) path(file string) string {
+	return filepath.Join(p.dir, file)
+}
+
+// readSources reads the source file for each execution node in the project.
+// The code is keyed by node name, and nodes without a source file are left
+// out.
+func (p project) readSources() (map[string]string, error) {
+	code := make(map[string]string)
+
+	for _, name := range p.config.ExecutionNodeNames() {
+		data, err := ioutil.ReadFile(p.path(name + ".tis"))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		code[name] = string(data)
+	}
+
+	return code, nil
+}
+
+// importSolution reads a solution saved by the game and writes the code for
+// each node to its own source file. Every node in the solution gets a file,
+// even if it's empty, so that no old code is left behind.
+func (p project) importSolution(save string) error {
+	f, err := os.Open(save)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	code, err := parseSave(p.config, f)
+	if err != nil {
+		return err
+	}
+
+	for name, nodeCode := range code {
+		if nodeCode != "" {
+			nodeCode += "\n"
+		}
+		if err := ioutil.WriteFile(p.path(name+".tis"), []byte(nodeCode), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// exportSolution writes the project's code as a solution the game can read. If
+// the save file is "-", it is written to stdout.
+func (p project) exportSolution(save string) error {
+	code, err := p.readSources()
+	if err != nil {
+		return err
+	}
+
+	if save == "-" {
+		return writeSave(p.config, code, os.Stdout)
+	}
+
+	f, err := os.Create(save)
+	if err != nil {
+		return err
+	}
+	if err = writeSave(p.config, code, f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
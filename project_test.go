@@ -0,0 +1,79 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/velovix/TISC-100/tis"
+)
+
+// parseTestConfig parses the given config JSON, failing the test if it's
+// invalid.
+func parseTestConfig(t *testing.T, configJSON string) tis.Config {
+	config, err := tis.ParseConfig([]byte(configJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return config
+}
+
+// newTestMachine creates a machine from the given config that uses the given
+// streams, and loads the code into it. Any error fails the test.
+func newTestMachine(t *testing.T, config tis.Config, code map[string]string, streams consoleStreams) *tis.Machine {
+	mach, err := tis.NewMachine(config, streams.inputs, streams.outputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mach.Load(code); err != nil {
+		t.Fatal(err)
+	}
+
+	return mach
+}
+
+// TestProjectFromDirectory tests that a project can be opened and read from
+// outside of its directory.
+func TestProjectFromDirectory(t *testing.T) {
+	p, err := projectFlags{dir: "example"}.open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.config.Name != "Example Project" {
+		t.Error("expected the example project's config, but got", p.config.Name)
+	}
+
+	code, err := p.readSources()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := code["0-0"]; !ok {
+		t.Error("expected the code for node 0-0 to be read from the project directory")
+	}
+}
+
+// TestProjectConfigFlag tests that the config file can be somewhere other than
+// the project directory.
+func TestProjectConfigFlag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tisc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := filepath.Join(dir, "other.json")
+	err = ioutil.WriteFile(config, []byte(`{"name": "Other", "nodes": [["e"]]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := projectFlags{dir: "example", config: config}.open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.config.Name != "Other" {
+		t.Error("expected the config given by the flag, but got", p.config.Name)
+	}
+}
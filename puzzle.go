@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/velovix/TISC-100/tis"
+)
+
+// maxTestCycles is how many cycles a test can run for before it is considered
+// stuck.
+const maxTestCycles = 100000
+
+// puzzleSpec describes a puzzle as a list of tests, each with the input given
+// to the machine and the output it is expected to write, like the game's
+// verification panel.
+type puzzleSpec struct {
+	Name  string       `json:"name"`
+	Tests []puzzleTest `json:"tests"`
+}
+
+// puzzleTest is a single set of inputs and expected outputs for a puzzle,
+// keyed by the name of the console input or output. Input and Output are a
+// shorthand for machines with only one input or output.
+type puzzleTest struct {
+	Input   []int            `json:"input"`
+	Output  []int            `json:"output"`
+	Inputs  map[string][]int `json:"inputs"`
+	Outputs map[string][]int `json:"outputs"`
+}
+
+// streams returns the test's inputs and expected outputs keyed by the names
+// the given config uses for them.
+func (pt puzzleTest) streams(config tis.Config) (map[string][]int, map[string][]int, error) {
+	inputs := make(map[string][]int)
+	for name, values := range pt.Inputs {
+		inputs[name] = values
+	}
+	if pt.Input != nil {
+		if len(config.Inputs) != 1 {
+			return nil, nil, errors.New("input can only be used with machines that have exactly one input")
+		}
+		inputs[config.Inputs[0].Name] = pt.Input
+	}
+
+	outputs := make(map[string][]int)
+	for name, values := range pt.Outputs {
+		outputs[name] = values
+	}
+	if pt.Output != nil {
+		if len(config.Outputs) != 1 {
+			return nil, nil, errors.New("output can only be used with machines that have exactly one output")
+		}
+		outputs[config.Outputs[0].Name] = pt.Output
+	}
+
+	return inputs, outputs, nil
+}
+
+// newPuzzleSpec creates a new puzzle spec from the given spec file location.
+func newPuzzleSpec(spec string) (puzzleSpec, error) {
+	var ps puzzleSpec
+
+	// Read the data from the spec file
+	data, err := ioutil.ReadFile(spec)
+	if err != nil {
+		return puzzleSpec{}, err
+	}
+
+	// Interpret the data as JSON and populate the puzzle spec
+	err = json.Unmarshal(data, &ps)
+	if err != nil {
+		return puzzleSpec{}, err
+	}
+
+	if len(ps.Tests) == 0 {
+		return puzzleSpec{}, errors.New("puzzle spec must have at least one test")
+	}
+
+	// Make sure every value is a valid TIS-100 number
+	for i, test := range ps.Tests {
+		all := [][]int{test.Input, test.Output}
+		for _, values := range test.Inputs {
+			all = append(all, values)
+		}
+		for _, values := range test.Outputs {
+			all = append(all, values)
+		}
+
+		for _, values := range all {
+			for _, val := range values {
+				if val != int(tis.NewNumber(val)) {
+					return puzzleSpec{}, fmt.Errorf("test %v has the value %v, which falls outside the range of an acceptable TIS-100 number", i+1, val)
+				}
+			}
+		}
+	}
+
+	return ps, nil
+}
+
+// puzzleResult is the outcome of running a machine against a single test.
+type puzzleResult struct {
+	outputs    map[string][]tis.Number // What the machine wrote to each console output
+	mismatches map[string]int          // The index of the first incorrect number for each output that has one
+	cycles     int                     // The cycle the last output was written on
+	err        error                   // Why the machine stopped before writing every output, if it did
+}
+
+// passed returns true if the machine wrote the expected output.
+func (pr puzzleResult) passed() bool {
+	return len(pr.mismatches) == 0
+}
+
+// runTest runs a machine with the given config and code against the test. The
+// machine runs until it has written as many numbers as the test expects, it
+// stops making progress, or it runs for too long.
+func runTest(config tis.Config, code map[string]string, test puzzleTest) (puzzleResult, error) {
+	inputValues, expected, err := test.streams(config)
+	if err != nil {
+		return puzzleResult{}, err
+	}
+
+	// Feed each input from the test and capture each output. Inputs the test
+	// doesn't mention are empty.
+	inputs := make(map[string]tis.InputStream)
+	for _, sc := range config.Inputs {
+		values := make([]tis.Number, len(inputValues[sc.Name]))
+		for i, val := range inputValues[sc.Name] {
+			values[i] = tis.NewNumber(val)
+		}
+		inputs[sc.Name] = tis.NewSliceInput(values)
+	}
+	captured := make(map[string]*tis.SliceOutput)
+	outputs := make(map[string]tis.OutputStream)
+	for _, sc := range config.Outputs {
+		captured[sc.Name] = &tis.SliceOutput{}
+		outputs[sc.Name] = captured[sc.Name]
+	}
+	for name := range expected {
+		if _, ok := captured[name]; !ok {
+			return puzzleResult{}, errors.New("the machine has no output named " + name)
+		}
+	}
+
+	// Create a fresh machine for the test
+	mach, err := tis.NewMachine(config, inputs, outputs)
+	if err != nil {
+		return puzzleResult{}, err
+	}
+	if err = mach.Load(code); err != nil {
+		return puzzleResult{}, err
+	}
+
+	// finished returns true once every output has been written in full
+	finished := func() bool {
+		for name, values := range expected {
+			if len(captured[name].Values) < len(values) {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Run until the machine is finished one way or another
+	var result puzzleResult
+	for !finished() {
+		if mach.Cycle() >= maxTestCycles {
+			result.err = fmt.Errorf("ran for %v cycles without finishing", maxTestCycles)
+			break
+		}
+		if !mach.Step() {
+			result.err = mach.Halted()
+			if result.err == nil {
+				result.err = mach.Deadlock()
+			}
+			if result.err == nil {
+				result.err = fmt.Errorf("stopped on cycle %v without finishing", mach.Cycle())
+			}
+			break
+		}
+	}
+
+	result.cycles = mach.Score().Cycles
+	result.outputs = make(map[string][]tis.Number)
+	result.mismatches = make(map[string]int)
+	for name, out := range captured {
+		result.outputs[name] = out.Values
+
+		// Find the first number that doesn't match, if any
+		for i, val := range expected[name] {
+			if i >= len(out.Values) || out.Values[i] != tis.Number(val) {
+				result.mismatches[name] = i
+				break
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// runPuzzle runs a machine with the given config and code against every test
+// in the puzzle spec and writes a report of the results. It returns true if
+// every test passed.
+func runPuzzle(spec puzzleSpec, config tis.Config, code map[string]string, w io.Writer) (bool, error) {
+	if spec.Name != "" {
+		fmt.Fprintln(w, spec.Name)
+	}
+
+	passed := 0
+	for i, test := range spec.Tests {
+		result, err := runTest(config, code, test)
+		if errors.As(err, new(tis.ParseErrors)) {
+			// The code is the same for every test, so problems with it
+			// aren't the test's fault
+			return false, err
+		} else if err != nil {
+			return false, fmt.Errorf("test %v: %v", i+1, err)
+		}
+
+		if result.passed() {
+			fmt.Fprintf(w, "Test %v: passed in %v cycles\n", i+1, result.cycles)
+			passed++
+			continue
+		}
+
+		_, expected, _ := test.streams(config)
+		for _, sc := range config.Outputs {
+			index, ok := result.mismatches[sc.Name]
+			if !ok {
+				continue
+			}
+
+			fmt.Fprintf(w, "Test %v: %v failed at index %v: expected %v but ", i+1, sc.Name, index, expected[sc.Name][index])
+			if got := result.outputs[sc.Name]; index < len(got) {
+				fmt.Fprintln(w, "got", got[index])
+			} else {
+				fmt.Fprintln(w, "got nothing")
+			}
+		}
+		if result.err != nil {
+			fmt.Fprintln(w, result.err)
+		}
+	}
+
+	fmt.Fprintf(w, "Passed %v of %v tests\n", passed, len(spec.Tests))
+	return passed == len(spec.Tests), nil
+}
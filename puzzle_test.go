@@ -0,0 +1,80 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/velovix/TISC-100/tis"
+)
+
+// exampleCode returns the configuration and code of the example project.
+func exampleCode(t *testing.T) (tis.Config, map[string]string) {
+	config, err := tis.LoadConfig("example/machine.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code := make(map[string]string)
+	for _, name := range []string{"0-0", "1-1"} {
+		data, err := ioutil.ReadFile("example/" + name + ".tis")
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		code[name] = string(data)
+	}
+
+	return config, code
+}
+
+// TestPuzzleSpecPasses tests that the example project passes its own spec.
+func TestPuzzleSpecPasses(t *testing.T) {
+	spec, err := newPuzzleSpec("example/spec.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, code := exampleCode(t)
+
+	for i, test := range spec.Tests {
+		result, err := runTest(config, code, test)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.passed() {
+			t.Error("expected test", i+1, "to pass, but it failed with output", result.outputs)
+		}
+		if result.cycles == 0 {
+			t.Error("expected test", i+1, "to take some cycles")
+		}
+	}
+}
+
+// TestPuzzleSpecMismatch tests that the first incorrect output is reported,
+// including when the machine stops before writing enough outputs.
+func TestPuzzleSpecMismatch(t *testing.T) {
+	config, code := exampleCode(t)
+
+	testCases := []struct {
+		test     puzzleTest
+		mismatch int
+	}{
+		{
+			test:     puzzleTest{Input: []int{1, 2, 3}, Output: []int{4, 5, 7}},
+			mismatch: 2},
+		{
+			test:     puzzleTest{Input: []int{1, 2, 3}, Output: []int{0, 5, 6}},
+			mismatch: 0},
+		{
+			test:     puzzleTest{Input: []int{1}, Output: []int{4, 5}},
+			mismatch: 1}}
+
+	for _, testCase := range testCases {
+		result, err := runTest(config, code, testCase.test)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mismatch, ok := result.mismatches["OUT.A"]; !ok || mismatch != testCase.mismatch {
+			t.Error("expected the first mismatch at index", testCase.mismatch, "but got", result.mismatches)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/velovix/TISC-100/tis"
+)
+
+// parseSave splits a solution saved by the game into the code for each
+// execution node in the given config. The game keeps every node's code in one
+// file, with each node's code following a line like "@0", where the number is
+// the node's place in the order given by ExecutionNodeNames.
+func parseSave(config tis.Config, r io.Reader) (map[string]string, error) {
+	names := config.ExecutionNodeNames()
+	code := make(map[string]string)
+
+	var current string
+	var lines []string
+	finish := func() {
+		if current != "" {
+			// The game separates nodes with a blank line that isn't part of
+			// the code
+			for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+				lines = lines[:len(lines)-1]
+			}
+			code[current] = strings.Join(lines, "\n")
+		}
+		lines = nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if strings.HasPrefix(line, "@") {
+			// The line starts a new node
+			index, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("at line %v, invalid node marker '%v'", lineNum, line)
+			}
+			if index >= len(names) {
+				return nil, fmt.Errorf("at line %v, node @%v doesn't exist in a machine with %v execution nodes", lineNum, index, len(names))
+			}
+			if _, ok := code[names[index]]; ok {
+				return nil, fmt.Errorf("at line %v, node @%v appears more than once", lineNum, index)
+			}
+
+			finish()
+			current = names[index]
+			code[current] = ""
+			continue
+		}
+
+		if current == "" {
+			if strings.TrimSpace(line) != "" {
+				return nil, fmt.Errorf("at line %v, code must come after a node marker like '@0'", lineNum)
+			}
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	finish()
+
+	if len(code) == 0 {
+		return nil, errors.New("no node markers were found")
+	}
+
+	return code, nil
+}
+
+// writeSave writes the code for each execution node in the given config as a
+// single solution file that the game can read. Nodes without code are written
+// as empty.
+func writeSave(config tis.Config, code map[string]string, w io.Writer) error {
+	for i, name := range config.ExecutionNodeNames() {
+		nodeCode := strings.TrimRight(strings.Replace(code[name], "\r\n", "\n", -1), "\n")
+		if nodeCode != "" {
+			nodeCode += "\n"
+		}
+
+		if _, err := fmt.Fprintf(w, "@%v\n%v\n", i, nodeCode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
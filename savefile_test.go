@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// saveConfig is a machine with a stack node in the middle of its execution
+// nodes, which the game leaves out of its numbering.
+const saveConfig = `{"nodes": [["e", "s"], ["e", "e"]]}`
+
+// gameSave is a solution for saveConfig as the game would save it.
+const gameSave = `@0
+MOV UP ACC
+ADD 3
+
+MOV ACC DOWN
+
+@1
+MOV UP RIGHT
+
+@2
+
+`
+
+func TestParseSave(t *testing.T) {
+	config := parseTestConfig(t, saveConfig)
+
+	code, err := parseSave(config, strings.NewReader(gameSave))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"0-0": "MOV UP ACC\nADD 3\n\nMOV ACC DOWN",
+		"0-1": "MOV UP RIGHT",
+		"1-1": ""}
+	if len(code) != len(expected) {
+		t.Errorf("expected %v nodes but got %v", len(expected), len(code))
+	}
+	for name, expectedCode := range expected {
+		if code[name] != expectedCode {
+			t.Errorf("expected node %v to have the code %q but got %q", name, expectedCode, code[name])
+		}
+	}
+}
+
+func TestParseSaveErrors(t *testing.T) {
+	config := parseTestConfig(t, saveConfig)
+
+	saves := []string{
+		"MOV UP ACC\n@0\n",
+		"@0\n@0\n",
+		"@3\nNOP\n",
+		"@A\nNOP\n",
+		""}
+	for _, save := range saves {
+		if _, err := parseSave(config, strings.NewReader(save)); err == nil {
+			t.Errorf("expected the save %q to be rejected", save)
+		}
+	}
+}
+
+func TestSaveRoundTrip(t *testing.T) {
+	config := parseTestConfig(t, saveConfig)
+
+	code, err := parseSave(config, strings.NewReader(gameSave))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err = writeSave(config, code, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != gameSave {
+		t.Errorf("expected the save to be written back as %q but got %q", gameSave, buf.String())
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/velovix/TISC-100/tis"
+)
+
+// webFiles is the browser view's page, script and styles, built into the
+// program so that serve works without anything else installed.
+//
+//go:embed web
+var webFiles embed.FS
+
+// server serves a browser view of a machine. The view gets the machine's
+// state as Server-Sent Events whenever it changes, and drives the machine by
+// posting to /control.
+type server struct {
+	sync.Mutex // Guards the driver and console streams, and the clients
+
+	driver
+	config  tis.Config
+	code    map[string][]string
+	streams []*panelStream
+	clients map[chan struct{}]bool // Told whenever the state changes
+
+	mux *http.ServeMux
+}
+
+// webState is the state of the machine as it's sent to the browser.
+type webState struct {
+	Name    string      `json:"name"`
+	Width   int         `json:"width"`
+	Height  int         `json:"height"`
+	Cycle   int         `json:"cycle"`
+	State   string      `json:"state"`
+	Nodes   []webNode   `json:"nodes"`
+	Streams []webStream `json:"streams"`
+}
+
+// webNode is the state of a node as it's sent to the browser.
+type webNode struct {
+	Name    string                `json:"name"`
+	Stack   bool                  `json:"stack"`
+	X       int                   `json:"x"`
+	Y       int                   `json:"y"`
+	Mode    string                `json:"mode"`
+	Code    []string              `json:"code,omitempty"`
+	Line    int                   `json:"line,omitempty"`
+	ACC     tis.Number            `json:"acc"`
+	BAK     tis.Number            `json:"bak"`
+	Waiting string                `json:"waiting,omitempty"`
+	Values  []tis.Number          `json:"values,omitempty"`
+	Sending map[string]tis.Number `json:"sending,omitempty"`
+}
+
+// webStream is a console stream and the latest numbers through it, as it's
+// sent to the browser.
+type webStream struct {
+	Name   string       `json:"name"`
+	Input  bool         `json:"input"`
+	Side   string       `json:"side"`
+	Pos    int          `json:"pos"`
+	Values []tis.Number `json:"values"`
+}
+
+// newServer creates a server for a machine with the given config and code.
+// The console streams are wrapped so that the browser can show what moves
+// through them, so the machine must be created with the streams afterwards.
+func newServer(config tis.Config, code map[string]string, streams *consoleStreams) *server {
+	s := &server{
+		config:  config,
+		code:    codeLines(code),
+		streams: watchStreams(config, streams),
+		clients: make(map[chan struct{}]bool),
+		mux:     http.NewServeMux()}
+
+	assets, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		panic(err)
+	}
+	s.mux.Handle("/", http.FileServer(http.FS(assets)))
+	s.mux.HandleFunc("/state", s.serveState)
+	s.mux.HandleFunc("/events", s.serveEvents)
+	s.mux.HandleFunc("/control", s.serveControl)
+
+	return s
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// drive runs the machine in the background until stop is closed.
+func (s *server) drive(stop <-chan struct{}) {
+	ticker := time.NewTicker(driveTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.Lock()
+			if s.tick() {
+				s.changed()
+			}
+			s.Unlock()
+		}
+	}
+}
+
+// changed tells every client that the state changed. The server must be
+// locked.
+func (s *server) changed() {
+	for client := range s.clients {
+		select {
+		case client <- struct{}{}:
+		default:
+			// The client hasn't caught up with the last change yet, and
+			// will see this one when it does
+		}
+	}
+}
+
+// snapshot takes a snapshot of the machine for the browser.
+func (s *server) snapshot() webState {
+	s.Lock()
+	defer s.Unlock()
+
+	state := webState{
+		Name:   s.config.Name,
+		Width:  len(s.config.Nodes[0]),
+		Height: len(s.config.Nodes),
+		Cycle:  s.mach.Cycle(),
+		State:  s.state()}
+	if state.Name == "" {
+		state.Name = programName
+	}
+
+	for _, n := range s.mach.Nodes() {
+		wn := webNode{
+			Name:    n.Name,
+			Stack:   n.Kind == tis.StackNode,
+			X:       n.X,
+			Y:       n.Y,
+			Mode:    n.Mode.String(),
+			ACC:     n.ACC,
+			BAK:     n.BAK,
+			Waiting: n.Waiting,
+			Values:  n.Values,
+			Sending: n.Sending}
+		if !wn.Stack {
+			wn.Code = s.code[n.Name]
+			wn.Line = n.Line
+		}
+		state.Nodes = append(state.Nodes, wn)
+	}
+
+	sides := make(map[string]tis.StreamConfig)
+	for _, sc := range append(append([]tis.StreamConfig(nil), s.config.Inputs...), s.config.Outputs...) {
+		sides[sc.Name] = sc
+	}
+	for _, ps := range s.streams {
+		state.Streams = append(state.Streams, webStream{
+			Name:   ps.name,
+			Input:  ps.input,
+			Side:   sides[ps.name].Side,
+			Pos:    sides[ps.name].Pos,
+			Values: append([]tis.Number{}, ps.values...)})
+	}
+
+	return state
+}
+
+// serveState responds with the state of the machine as JSON.
+func (s *server) serveState(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.snapshot())
+}
+
+// serveEvents streams the state of the machine as Server-Sent Events, starting
+// with the state it's in now and then every time it changes.
+func (s *server) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming isn't supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	client := make(chan struct{}, 1)
+	client <- struct{}{}
+	s.Lock()
+	s.clients[client] = true
+	s.Unlock()
+	defer func() {
+		s.Lock()
+		delete(s.clients, client)
+		s.Unlock()
+	}()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-client:
+			data, err := json.Marshal(s.snapshot())
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// serveControl runs, pauses, steps or fast-forwards the machine, depending on
+// the action posted.
+func (s *server) serveControl(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "controls must be posted", http.StatusMethodNotAllowed)
+		return
+	}
+
+	action := r.FormValue("action")
+	s.Lock()
+	defer s.Unlock()
+
+	switch action {
+	case "run":
+		s.run()
+	case "pause":
+		s.pause()
+	case "step":
+		s.stepOnce()
+	case "fast":
+		s.fastForward()
+	default:
+		http.Error(w, "unknown action '"+action+"'", http.StatusBadRequest)
+		return
+	}
+
+	s.changed()
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/velovix/TISC-100/tis"
+)
+
+// newTestServer serves a machine that reads 7 from IN, adds 1 to it and
+// sends it to an idle node.
+func newTestServer(t *testing.T) *httptest.Server {
+	config := parseTestConfig(t, `{
+		"name": "Serve",
+		"nodes": [["e", "e"]],
+		"inputs": [{"name": "IN", "side": "top", "pos": 0}]}`)
+	code := map[string]string{"0-0": "mov up acc\nadd 1\nmov acc right\n"}
+	streams := consoleStreams{
+		inputs:  map[string]tis.InputStream{"IN": tis.NewSliceInput([]tis.Number{7})},
+		outputs: map[string]tis.OutputStream{}}
+
+	srv := newServer(config, code, &streams)
+	srv.mach = newTestMachine(t, config, code, streams)
+
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// TestServeControl tests that the machine can be stepped from the browser,
+// and that its state is served as JSON.
+func TestServeControl(t *testing.T) {
+	ts := newTestServer(t)
+
+	for i := 0; i < 2; i++ {
+		resp, err := http.PostForm(ts.URL+"/control", url.Values{"action": {"step"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("expected stepping to succeed, got %v", resp.Status)
+		}
+	}
+
+	resp, err := http.Get(ts.URL + "/state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var state webState
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		t.Fatal(err)
+	}
+
+	if state.Name != "Serve" || state.Cycle != 2 || state.State != "PAUSED" {
+		t.Errorf("expected Serve to be paused on cycle 2, got %+v", state)
+	}
+	if len(state.Nodes) != 2 {
+		t.Fatalf("expected 2 nodes, got %+v", state.Nodes)
+	}
+	if n := state.Nodes[0]; n.ACC != 8 || n.Line != 3 || n.Mode != "RUN" || len(n.Code) != 3 {
+		t.Errorf("expected node 0-0 to be on line 3 with 8 in ACC, got %+v", n)
+	}
+	if n := state.Nodes[1]; n.Mode != "IDLE" || n.Code != nil {
+		t.Errorf("expected node 1-0 to be idle, got %+v", n)
+	}
+	if len(state.Streams) != 1 || len(state.Streams[0].Values) != 1 || state.Streams[0].Values[0] != 7 {
+		t.Errorf("expected IN to have given 7, got %+v", state.Streams)
+	}
+
+	for _, tc := range []struct {
+		method string
+		action string
+		status int
+	}{
+		{http.MethodPost, "jump", http.StatusBadRequest},
+		{http.MethodGet, "step", http.StatusMethodNotAllowed}} {
+		req, err := http.NewRequest(tc.method, ts.URL+"/control", strings.NewReader("action="+tc.action))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.status {
+			t.Errorf("expected %v %v to fail with %v, got %v", tc.method, tc.action, tc.status, resp.StatusCode)
+		}
+	}
+}
+
+// TestServeEvents tests that the state is sent as soon as the browser
+// connects, and again when it changes.
+func TestServeEvents(t *testing.T) {
+	ts := newTestServer(t)
+
+	resp, err := http.Get(ts.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected an event stream, got %q", ct)
+	}
+
+	events := bufio.NewScanner(resp.Body)
+	next := func() webState {
+		for events.Scan() {
+			if data := strings.TrimPrefix(events.Text(), "data: "); data != events.Text() {
+				var state webState
+				if err := json.Unmarshal([]byte(data), &state); err != nil {
+					t.Fatal(err)
+				}
+				return state
+			}
+		}
+		t.Fatal("expected another event")
+		return webState{}
+	}
+
+	if state := next(); state.Cycle != 0 {
+		t.Errorf("expected the first event to be on cycle 0, got %v", state.Cycle)
+	}
+
+	step, err := http.PostForm(ts.URL+"/control", url.Values{"action": {"step"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	step.Body.Close()
+	if state := next(); state.Cycle != 1 {
+		t.Errorf("expected an event for cycle 1 after stepping, got %v", state.Cycle)
+	}
+}
+
+// TestServeAssets tests that the browser view is served from the assets
+// built into the program.
+func TestServeAssets(t *testing.T) {
+	ts := newTestServer(t)
+
+	for _, path := range []string{"/", "/app.js", "/style.css"} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("expected %v to be served, got %v", path, resp.Status)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+)
+
+// socketConsole is console input and output over a TCP or Unix socket, used
+// in place of stdin and stdout. Once it's listening, the first read or write
+// waits for a client to connect, so the machine waits for one too. Only one
+// client is served.
+type socketConsole struct {
+	network string
+	address string
+
+	ln     net.Listener
+	accept sync.Once
+	conn   net.Conn
+	err    error
+}
+
+// newSocketConsole creates a console for the given address, which is either
+// HOST:PORT for TCP or unix:PATH for a Unix socket. It doesn't listen until
+// listen is called.
+func newSocketConsole(address string) (*socketConsole, error) {
+	sc := &socketConsole{
+		network: "tcp",
+		address: address}
+
+	switch {
+	case strings.HasPrefix(address, "unix:"):
+		sc.network, sc.address = "unix", strings.TrimPrefix(address, "unix:")
+	case strings.HasPrefix(address, "tcp:"):
+		sc.address = strings.TrimPrefix(address, "tcp:")
+	}
+	if sc.address == "" {
+		return nil, errors.New("expected HOST:PORT or unix:PATH to listen on")
+	}
+
+	return sc, nil
+}
+
+// listen starts listening for a client.
+func (sc *socketConsole) listen() error {
+	ln, err := net.Listen(sc.network, sc.address)
+	if err != nil {
+		return err
+	}
+
+	sc.ln = ln
+	return nil
+}
+
+// addr returns the address the console is listening on, which has the real
+// port if the port was left for the system to pick.
+func (sc *socketConsole) addr() string {
+	if sc.network == "unix" {
+		return "unix:" + sc.ln.Addr().String()
+	}
+
+	return sc.ln.Addr().String()
+}
+
+// connect waits for a client to connect if one hasn't yet. Once a client
+// connects, no others are accepted.
+func (sc *socketConsole) connect() error {
+	sc.accept.Do(func() {
+		if sc.ln == nil {
+			sc.err = errors.New("the console isn't listening")
+			return
+		}
+		sc.conn, sc.err = sc.ln.Accept()
+		sc.ln.Close()
+	})
+
+	return sc.err
+}
+
+// Read reads console input sent by the client.
+func (sc *socketConsole) Read(p []byte) (int, error) {
+	if err := sc.connect(); err != nil {
+		return 0, err
+	}
+
+	return sc.conn.Read(p)
+}
+
+// Write sends console output to the client.
+func (sc *socketConsole) Write(p []byte) (int, error) {
+	if err := sc.connect(); err != nil {
+		return 0, err
+	}
+
+	return sc.conn.Write(p)
+}
+
+// Close disconnects the client, or stops waiting for one.
+func (sc *socketConsole) Close() error {
+	if sc.ln != nil {
+		sc.ln.Close()
+	}
+
+	// Wait for any client that's connecting, which fails now that the
+	// listener is closed
+	if sc.connect() != nil {
+		return nil
+	}
+	return sc.conn.Close()
+}
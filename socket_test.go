@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/velovix/TISC-100/tis"
+)
+
+func TestSocketConsoleAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		network string
+		path    string
+	}{
+		{"localhost:9000", "tcp", "localhost:9000"},
+		{"tcp::9000", "tcp", ":9000"},
+		{"unix:/tmp/tis.sock", "unix", "/tmp/tis.sock"}}
+	for _, test := range tests {
+		sc, err := newSocketConsole(test.address)
+		if err != nil {
+			t.Errorf("expected %q to be valid, got %v", test.address, err)
+			continue
+		}
+		if sc.network != test.network || sc.address != test.path {
+			t.Errorf("expected %q to be %v %v, got %v %v", test.address, test.network, test.path, sc.network, sc.address)
+		}
+	}
+
+	for _, address := range []string{"unix:", "tcp:"} {
+		if _, err := newSocketConsole(address); err == nil {
+			t.Errorf("expected %q to be rejected", address)
+		}
+	}
+}
+
+// TestSocketConsole tests that a machine waits for a client to connect, reads
+// its input from the client until it stops sending, and sends its output back.
+func TestSocketConsole(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tisc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, address := range []string{"127.0.0.1:0", "unix:" + filepath.Join(dir, "console.sock")} {
+		sc, err := newSocketConsole(address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sc.listen(); err != nil {
+			t.Fatal(err)
+		}
+
+		config := parseTestConfig(t, `{
+			"nodes": [["e"]],
+			"inputs": [{"name": "IN", "side": "top", "pos": 0}],
+			"outputs": [{"name": "OUT", "side": "bottom", "pos": 0}]}`)
+		mach := newTestMachine(t, config,
+			map[string]string{"0-0": "mov up acc\nadd 1\nmov acc down\n"},
+			consoleStreams{
+				inputs:  map[string]tis.InputStream{"IN": tis.NewTextInput(bufio.NewReader(sc))},
+				outputs: map[string]tis.OutputStream{"OUT": tis.NewTextOutput(sc)}})
+
+		received := make(chan string)
+		go func() {
+			network, path := "tcp", sc.addr()
+			if sc.network == "unix" {
+				network, path = "unix", sc.address
+			}
+			conn, err := net.Dial(network, path)
+			if err != nil {
+				received <- err.Error()
+				return
+			}
+			defer conn.Close()
+
+			conn.Write([]byte("1\n2\n3\n"))
+			if c, ok := conn.(interface{ CloseWrite() error }); ok {
+				c.CloseWrite()
+			}
+			out, _ := ioutil.ReadAll(conn)
+			received <- string(out)
+		}()
+
+		for mach.Step() {
+		}
+		if err := sc.Close(); err != nil {
+			t.Error(err)
+		}
+
+		if out := <-received; out != "2\n3\n4\n" {
+			t.Errorf("expected the client on %v to receive 2, 3 and 4, got %q", address, out)
+		}
+	}
+}
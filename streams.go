@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/velovix/TISC-100/tis"
+)
+
+// streamFiles is a flag that maps console input or output names to files. It
+// can be given more than once.
+type streamFiles map[string]string
+
+func (sf streamFiles) String() string {
+	var pairs []string
+	for name, file := range sf {
+		pairs = append(pairs, name+"="+file)
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func (sf streamFiles) Set(val string) error {
+	parts := strings.SplitN(val, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return errors.New("expected NAME=FILE")
+	}
+
+	sf[parts[0]] = parts[1]
+	return nil
+}
+
+// streamFlags are the command line flags that send console inputs and outputs
+// to files or a socket.
+type streamFlags struct {
+	inputFiles  streamFiles
+	outputFiles streamFiles
+	listen      string
+}
+
+// register adds the stream flags to the given flag set.
+func (sf *streamFlags) register(fs *flag.FlagSet) {
+	sf.inputFiles = make(streamFiles)
+	sf.outputFiles = make(streamFiles)
+
+	fs.Var(sf.inputFiles, "in", "read the named console input from a file, as `NAME=FILE`")
+	fs.Var(sf.outputFiles, "out", "write the named console output to a file, as `NAME=FILE`")
+}
+
+// registerListen adds the flag that moves console input and output from stdin
+// and stdout to a socket to the given flag set. It is only registered by
+// commands that can wait on a client without freezing.
+func (sf *streamFlags) registerListen(fs *flag.FlagSet) {
+	fs.StringVar(&sf.listen, "listen", "", "use a client connecting to `ADDRESS` for console input and output instead of stdin and stdout, as HOST:PORT or unix:PATH")
+}
+
+// consoleStreams holds the streams created for a machine's console inputs and
+// outputs.
+type consoleStreams struct {
+	inputs  map[string]tis.InputStream
+	outputs map[string]tis.OutputStream
+
+	stdin  *tis.TextInput              // The input reading from stdin or the socket, if there is one
+	socket *socketConsole              // Used in place of stdin and stdout, if it was asked for
+	images map[string]*tis.ImageOutput // Image outputs keyed by the PNG file they're saved to
+	files  []*os.File                  // Files opened for inputs and outputs
+}
+
+// open creates a stream for each of the project's console inputs and outputs.
+// Streams that were given a file on the command line use it. The rest use
+// stdin and stdout, and only one input can use stdin. If stdin is nil, every
+// input needs a file. If more than one output uses stdout, each number is
+// marked with the name of its output. If a socket was asked for, it's used in
+// place of stdin and stdout, though it doesn't listen until the streams'
+// listen is called. Image outputs are saved as a PNG to their file, or to
+// NAME.png in the project directory if they weren't given one.
+func (sf streamFlags) open(p project, stdin *bufio.Reader, stdout io.Writer) (consoleStreams, error) {
+	// Make sure every file is for a real input or output
+	for name := range sf.inputFiles {
+		if !hasStream(p.config.Inputs, name) {
+			return consoleStreams{}, errors.New("the machine has no input named " + name)
+		}
+	}
+	for name := range sf.outputFiles {
+		if !hasStream(p.config.Outputs, name) {
+			return consoleStreams{}, errors.New("the machine has no output named " + name)
+		}
+	}
+
+	streams := consoleStreams{
+		inputs:  make(map[string]tis.InputStream),
+		outputs: make(map[string]tis.OutputStream),
+		images:  make(map[string]*tis.ImageOutput)}
+
+	source := "stdin"
+	if sf.listen != "" {
+		socket, err := newSocketConsole(sf.listen)
+		if err != nil {
+			return consoleStreams{}, err
+		}
+		streams.socket = socket
+		stdin, stdout = bufio.NewReader(socket), socket
+		source = "the console socket"
+	}
+
+	for _, sc := range p.config.Inputs {
+		if file, ok := sf.inputFiles[sc.Name]; ok {
+			f, err := os.Open(file)
+			if err != nil {
+				return consoleStreams{}, err
+			}
+			streams.files = append(streams.files, f)
+			in := tis.NewTextInput(f)
+			in.Errors = os.Stderr
+			streams.inputs[sc.Name] = in
+			continue
+		}
+
+		if stdin == nil {
+			return consoleStreams{}, errors.New("stdin isn't available for console input, use -in to give " + sc.Name + " a file")
+		}
+		if streams.stdin != nil {
+			return consoleStreams{}, errors.New("only one input can read from " + source + ", use -in to give " + sc.Name + " a file")
+		}
+		streams.stdin = tis.NewTextInput(stdin)
+		streams.stdin.Name = sc.Name
+		streams.stdin.Errors = os.Stderr
+		streams.inputs[sc.Name] = streams.stdin
+	}
+
+	var stdoutOutputs []*tis.TextOutput
+	for _, sc := range p.config.Outputs {
+		if sc.Type == "image" {
+			file, ok := sf.outputFiles[sc.Name]
+			if !ok {
+				file = p.path(sc.Name + ".png")
+			}
+			iout := tis.NewImageOutput(sc.Width, sc.Height)
+			streams.images[file] = iout
+			streams.outputs[sc.Name] = iout
+			continue
+		}
+
+		if file, ok := sf.outputFiles[sc.Name]; ok {
+			f, err := os.Create(file)
+			if err != nil {
+				return consoleStreams{}, err
+			}
+			streams.files = append(streams.files, f)
+			streams.outputs[sc.Name] = tis.NewTextOutput(f)
+			continue
+		}
+
+		out := tis.NewTextOutput(stdout)
+		out.Prefix = sc.Name
+		stdoutOutputs = append(stdoutOutputs, out)
+		streams.outputs[sc.Name] = out
+	}
+	if len(stdoutOutputs) == 1 {
+		// There's no need to tell outputs apart if there's only one
+		stdoutOutputs[0].Prefix = ""
+	}
+
+	return streams, nil
+}
+
+// renderImages draws the image outputs to a terminal as they change until
+// done is closed. The images are always drawn in the same order.
+func (cs consoleStreams) renderImages(w io.Writer, done <-chan struct{}) {
+	var files []string
+	for file := range cs.images {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	var images []*tis.ImageOutput
+	for _, file := range files {
+		images = append(images, cs.images[file])
+	}
+
+	tis.RenderLive(w, images, done)
+}
+
+// listen starts listening on the console socket, if there is one, and says
+// where clients can connect.
+func (cs consoleStreams) listen() error {
+	if cs.socket == nil {
+		return nil
+	}
+	if err := cs.socket.listen(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, "Waiting for a console client on", cs.socket.addr())
+	return nil
+}
+
+// close flushes everything written to the streams and closes their files,
+// after saving the final frame of each image output to its PNG file. The
+// console socket's client is disconnected.
+func (cs consoleStreams) close() error {
+	if cs.socket != nil {
+		if err := cs.socket.Close(); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range cs.files {
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+
+	return cs.saveImages()
+}
+
+// saveImages writes the final frame of each image output to its PNG file.
+func (cs consoleStreams) saveImages() error {
+	for file, iout := range cs.images {
+		f, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		if err = iout.WritePNG(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err = f.Close(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isTerminal returns true if the given file is a terminal rather than a pipe
+// or a regular file.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// hasStream returns true if one of the given streams has the given name.
+func hasStream(streams []tis.StreamConfig, name string) bool {
+	for _, sc := range streams {
+		if sc.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
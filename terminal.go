@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Escape codes for taking over a terminal's screen.
+const (
+	ansiEnterScreen = "\x1b[?1049h\x1b[?25l\x1b[?7l" // Switch to the alternate screen, hide the cursor and stop lines from wrapping
+	ansiLeaveScreen = "\x1b[?7h\x1b[?25h\x1b[?1049l" // Undo ansiEnterScreen
+	ansiHome        = "\x1b[H"                       // Move the cursor to the top left
+	ansiClearLine   = "\x1b[K"                       // Clear the rest of the line
+	ansiClearScreen = "\x1b[J"                       // Clear the rest of the screen
+	ansiReset       = "\x1b[0m"
+	ansiBold        = "\x1b[1m"
+	ansiDim         = "\x1b[2m"
+	ansiReverse     = "\x1b[7m"
+)
+
+// terminal is the terminal the program is running in, put into a mode where
+// every key press can be read as soon as it happens. It's driven with stty,
+// so it works over SSH and anywhere else stty does.
+type terminal struct {
+	saved string // The terminal's settings before it was taken over
+}
+
+// openTerminal takes over the terminal on stdin and stdout. Closing the
+// terminal puts it back the way it was.
+func openTerminal() (*terminal, error) {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return nil, errors.New("stdin and stdout must be a terminal")
+	}
+
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = stty("-icanon", "-echo", "min", "1"); err != nil {
+		return nil, err
+	}
+
+	fmt.Print(ansiEnterScreen)
+	return &terminal{
+		saved: strings.TrimSpace(saved)}, nil
+}
+
+// size returns the number of rows and columns in the terminal. If they can't
+// be found, the size of a classic terminal is assumed.
+func (t *terminal) size() (int, int) {
+	out, err := stty("size")
+	if err != nil {
+		return 24, 80
+	}
+
+	var rows, cols int
+	if _, err := fmt.Sscan(out, &rows, &cols); err != nil || rows <= 0 || cols <= 0 {
+		return 24, 80
+	}
+
+	return rows, cols
+}
+
+// close puts the terminal back the way it was.
+func (t *terminal) close() error {
+	fmt.Print(ansiLeaveScreen)
+	_, err := stty(t.saved)
+	return err
+}
+
+// stty runs stty on stdin with the given arguments and returns its output.
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("running stty: %v", err)
+	}
+
+	return string(out), nil
+}
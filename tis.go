@@ -1,66 +1,626 @@
 package main
 
 import (
+	"bufio"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
+	"net/http"
 	"os"
-	"strconv"
+	"os/signal"
+	"text/tabwriter"
+
+	"github.com/velovix/TISC-100/tis"
 )
 
-func main() {
-	// Load the machine config file
-	machConfig, err := newMachineConfig("./machine.json")
+const programName = "TISC-100"
+
+// The statuses the program exits with.
+const (
+	exitOK     = 0
+	exitFailed = 1 // The solution deadlocked or failed a test
+	exitUsage  = 2 // The command line was invalid
+	exitError  = 3 // The project couldn't be loaded, assembled or saved
+	exitHalted = 4 // A node stopped the machine with HCF
+)
+
+// command is one of the subcommands of the command line interface. It is
+// given the arguments that come after its name and returns the status to exit
+// with.
+type command struct {
+	name    string
+	args    string // The arguments the command takes, as shown in its usage
+	summary string
+	run     func(cmd command, args []string) int
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"run", "[flags]", "Run the project with console input from stdin.", runCommand},
+		{"test", "[flags] [spec]", "Run the project against the tests in a puzzle spec, spec.json by default.", testCommand},
+		{"debug", "[flags]", "Run the project under an interactive debugger.", debugCommand},
+		{"tui", "[flags]", "Run the project in a full-screen view of its nodes, like the game.", tuiCommand},
+		{"serve", "[flags]", "Run the project behind a local web server, viewed and controlled from a browser.", serveCommand},
+		{"import", "[flags] save", "Split a solution saved by the game into a .tis file for each node.", importCommand},
+		{"export", "[flags] [save]", "Join the project's .tis files into a solution the game can read, written to stdout by default.", exportCommand},
+		{"help", "[command]", "Print help for a command.", helpCommand}}
+}
+
+// usage writes the list of commands.
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %v <command> [arguments]\n\nCommands:\n", programName)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8v %v\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nRun '%v help <command>' for more about a command.\n", programName)
+}
+
+// findCommand returns the command with the given name.
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+
+	return command{}, false
+}
+
+// flagSet creates the flag set for a command, with usage help that describes
+// the command.
+func (cmd command) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ExitOnError)
+	fs.Usage = func() {
+		w := fs.Output()
+		fmt.Fprintf(w, "Usage: %v %v %v\n\n%v\n", programName, cmd.name, cmd.args, cmd.summary)
+
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintln(w, "\nFlags:")
+			fs.PrintDefaults()
+		}
+	}
+
+	return fs
+}
+
+// fail prints an error message to stderr and returns the given exit status.
+func fail(status int, a ...interface{}) int {
+	fmt.Fprintln(os.Stderr, a...)
+	return status
+}
+
+// badUsage prints an error message and the command's usage to stderr, and
+// returns the exit status for bad usage.
+func badUsage(fs *flag.FlagSet, msg string) int {
+	fmt.Fprintln(fs.Output(), msg)
+	fs.Usage()
+	return exitUsage
+}
+
+// assemble opens the project and its console streams, and loads its code into
+// a new machine. If something goes wrong, an error has already been printed
+// and the status to exit with is returned.
+func assemble(pf projectFlags, sf streamFlags, stdin *bufio.Reader) (*tis.Machine, consoleStreams, int) {
+	p, code, streams, status := openProject(pf, sf, stdin, os.Stdout)
+	if status != exitOK {
+		return nil, consoleStreams{}, status
+	}
+
+	mach, status := build(p, code, streams)
+	return mach, streams, status
+}
+
+// openProject opens the project, reads its code and opens its console
+// streams, which use the given stdin and stdout when they aren't given a file.
+// Errors are handled like in assemble.
+func openProject(pf projectFlags, sf streamFlags, stdin *bufio.Reader, stdout io.Writer) (project, map[string]string, consoleStreams, int) {
+	p, err := pf.open()
+	if err != nil {
+		return project{}, nil, consoleStreams{}, fail(exitError, "Error parsing machine config:", err)
+	}
+
+	// Load a source file for each executable node
+	code, err := p.readSources()
 	if err != nil {
-		fmt.Println("Error parsing machine.json:", err)
-		os.Exit(1)
+		return project{}, nil, consoleStreams{}, fail(exitError, "Error opening code:", err)
 	}
 
+	streams, err := sf.open(p, stdin, stdout)
+	if err != nil {
+		return project{}, nil, consoleStreams{}, fail(exitError, "Error opening console streams:", err)
+	}
+
+	return p, code, streams, exitOK
+}
+
+// build creates a machine for the project that uses the given console streams
+// and loads the code into it. Errors are handled like in assemble.
+func build(p project, code map[string]string, streams consoleStreams) (*tis.Machine, int) {
 	// Create a machine from the config information
-	mach, err := newMachine(machConfig)
+	mach, err := tis.NewMachine(p.config, streams.inputs, streams.outputs)
 	if err != nil {
-		fmt.Println("Error assembling TIS-100:", err)
+		return nil, fail(exitError, "Error assembling TIS-100:", err)
 	}
 
-	// Load a source file for each executable node
-	for y, row := range mach.nodes {
-		for x, elem := range row {
-			switch t := elem.(type) {
-			case *executionNode:
-				// The node is an execution node, so it may have a source file
-				// associated with it
-
-				file := strconv.Itoa(x) + "-" + strconv.Itoa(y) + ".tis"
-
-				// Try to load a source file for the node
-				data, err := ioutil.ReadFile(file)
-				if err != nil && !os.IsNotExist(err) {
-					fmt.Println("Error opening code for node "+file+":\n", err)
-				}
-
-				// Create a scanner from the code
-				scan := newScanner()
-				scan.add(string(data))
-				scan.add("\n") // Add a newline to the end of the file in case one isn't there
-
-				// Lex tokens out of the code
-				lex := newLexer(scan)
-				err = lex.lex()
-				if err != nil {
-					fmt.Println("Error opening code for node "+file+":\n", err)
-				}
-
-				// Parse the tokens
-				parse := newParser(lex)
-				err = parse.parse(t)
-				if err != nil {
-					fmt.Println("Error opening code for node "+file+":\n", err)
-				}
-			}
+	// Scan, lex and parse the code into the nodes
+	if err = mach.Load(code); err != nil {
+		return nil, loadFailed(p, err)
+	}
+
+	// Only wait on console clients once the machine is ready for them
+	if err = streams.listen(); err != nil {
+		return nil, fail(exitError, "Error opening console socket:", err)
+	}
+
+	return mach, exitOK
+}
+
+// loadFailed prints every problem found while loading a project's code into a
+// machine, and returns the status to exit with. Each problem is shown with the
+// file, line and column it's at, followed by the line itself.
+func loadFailed(p project, err error) int {
+	var errs tis.ParseErrors
+	if !errors.As(err, &errs) {
+		return fail(exitError, "Error opening code:", err)
+	}
+
+	for _, e := range errs {
+		fmt.Fprintf(os.Stderr, "%v:%v:%v: %v\n", p.path(e.Node+".tis"), e.Line, e.Column, e.Message)
+		fmt.Fprintf(os.Stderr, "%v\n", e.Snippet())
+	}
+
+	if len(errs) == 1 {
+		return fail(exitError, "Error opening code: found 1 problem")
+	}
+	return fail(exitError, fmt.Sprintf("Error opening code: found %v problems", len(errs)))
+}
+
+// startRecording opens the given file and passes a buffered writer for it to
+// start, which should begin recording the machine to it. Nothing is recorded if
+// the file name is empty. The returned function passes nil to start to stop
+// recording, and flushes the recording to the file.
+func startRecording(file string, start func(io.Writer)) (func() error, error) {
+	if file == "" {
+		return func() error { return nil }, nil
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(f)
+	start(w)
+
+	return func() error {
+		start(nil)
+		if err := w.Flush(); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}, nil
+}
+
+// recordingFlags are the command line flags that record what the machine does
+// to files.
+type recordingFlags struct {
+	trace *string
+	vcd   *string
+}
+
+// register adds the recording flags to the given flag set.
+func (rf *recordingFlags) register(fs *flag.FlagSet) {
+	rf.trace = fs.String("trace", "", "write a JSON record of every instruction run to `FILE`, one per line")
+	rf.vcd = fs.String("vcd", "", "write a waveform of port traffic, ACC and BAK to `FILE` as a Value Change Dump")
+}
+
+// start starts every recording that was asked for. The returned function
+// stops them all and flushes them to their files.
+func (rf recordingFlags) start(mach *tis.Machine) (func() error, error) {
+	stopTrace, err := startRecording(*rf.trace, mach.Trace)
+	if err != nil {
+		return nil, err
+	}
+	stopVCD, err := startRecording(*rf.vcd, mach.RecordVCD)
+	if err != nil {
+		stopTrace()
+		return nil, err
+	}
+
+	return func() error {
+		traceErr := stopTrace()
+		if err := stopVCD(); err != nil {
+			return err
 		}
+		return traceErr
+	}, nil
+}
+
+// finish flushes the machine's console outputs and checks whether it was
+// halted or deadlocked.
+func finish(mach *tis.Machine, streams consoleStreams) int {
+	if err := streams.close(); err != nil {
+		return fail(exitError, "Error saving console output:", err)
+	}
+
+	// Report which node stopped the machine if one ran HCF
+	if err := mach.Halted(); err != nil {
+		return fail(exitHalted, "TIS-100 halted:", err)
+	}
+
+	// Fail if the machine stopped because it got stuck
+	if err := mach.Deadlock(); err != nil {
+		return fail(exitFailed, "Error running TIS-100:", err)
+	}
+
+	return exitOK
+}
+
+// registerUtilization adds the flag that prints how much of the run each node
+// spent in each mode to the given flag set, and returns the flag's value.
+func registerUtilization(fs *flag.FlagSet) *bool {
+	return fs.Bool("utilization", false, "print how much of the run each node spent running, reading, writing and idle")
+}
+
+// printUtilization writes a table of how much of the run each node spent in
+// each mode, which shows the nodes that sat idle or stalled on their ports.
+func printUtilization(w io.Writer, mach *tis.Machine) {
+	modes := []tis.Mode{tis.ModeRun, tis.ModeRead, tis.ModeWrite, tis.ModeIdle}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprint(tw, "NODE")
+	for _, mode := range modes {
+		fmt.Fprintf(tw, "\t%6v", mode)
+	}
+	fmt.Fprintln(tw)
+
+	for _, n := range mach.Nodes() {
+		name := n.Name
+		if n.Kind == tis.StackNode {
+			name += " (stack)"
+		}
+		fmt.Fprint(tw, name)
+		for _, mode := range modes {
+			fmt.Fprintf(tw, "\t%5.1f%%", n.Modes.Percent(mode))
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+}
+
+func runCommand(cmd command, args []string) int {
+	fs := cmd.flagSet()
+	var pf projectFlags
+	pf.register(fs)
+	pf.registerStrict(fs)
+	var sf streamFlags
+	sf.register(fs)
+	sf.registerListen(fs)
+	scoreJSON := fs.Bool("json", false, "print the score as JSON")
+	utilization := registerUtilization(fs)
+	var rf recordingFlags
+	rf.register(fs)
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		return badUsage(fs, "Unexpected argument '"+fs.Arg(0)+"'")
+	}
+
+	mach, streams, status := assemble(pf, sf, bufio.NewReader(os.Stdin))
+	if status != exitOK {
+		return status
+	}
+	stopRecording, err := rf.start(mach)
+	if err != nil {
+		return fail(exitError, "Error opening recording:", err)
 	}
 
 	// Start the machine
-	mach.start()
+	mach.Start()
+
+	if len(streams.images) > 0 && isTerminal(os.Stderr) {
+		// Show the image outputs as they're drawn
+		streams.renderImages(os.Stderr, mach.Done())
+	}
+
+	<-mach.Done()
+	if err := stopRecording(); err != nil {
+		return fail(exitError, "Error writing recording:", err)
+	}
+
+	// Report how the solution did
+	if *scoreJSON {
+		fmt.Fprintln(os.Stderr, mach.Score().JSON())
+	} else {
+		fmt.Fprintln(os.Stderr, mach.Score())
+	}
+	if *utilization {
+		printUtilization(os.Stderr, mach)
+	}
+
+	return finish(mach, streams)
+}
+
+func testCommand(cmd command, args []string) int {
+	fs := cmd.flagSet()
+	var pf projectFlags
+	pf.register(fs)
+	pf.registerStrict(fs)
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		return badUsage(fs, "Unexpected argument '"+fs.Arg(1)+"'")
+	}
+
+	p, err := pf.open()
+	if err != nil {
+		return fail(exitError, "Error parsing machine config:", err)
+	}
+	code, err := p.readSources()
+	if err != nil {
+		return fail(exitError, "Error opening code:", err)
+	}
+
+	specFile := fs.Arg(0)
+	if specFile == "" {
+		specFile = p.path("spec.json")
+	}
+	spec, err := newPuzzleSpec(specFile)
+	if err != nil {
+		return fail(exitError, "Error parsing "+specFile+":", err)
+	}
+
+	passed, err := runPuzzle(spec, p.config, code, os.Stdout)
+	if errors.As(err, new(tis.ParseErrors)) {
+		return loadFailed(p, err)
+	} else if err != nil {
+		return fail(exitError, "Error assembling TIS-100:", err)
+	}
+	if !passed {
+		return exitFailed
+	}
+
+	return exitOK
+}
+
+func debugCommand(cmd command, args []string) int {
+	fs := cmd.flagSet()
+	var pf projectFlags
+	pf.register(fs)
+	pf.registerStrict(fs)
+	var sf streamFlags
+	sf.register(fs)
+	sf.registerListen(fs)
+	var rf recordingFlags
+	rf.register(fs)
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		return badUsage(fs, "Unexpected argument '"+fs.Arg(0)+"'")
+	}
+
+	// Console input shares stdin with the debugger, so they need to share a
+	// reader too
+	stdin := bufio.NewReader(os.Stdin)
+	mach, streams, status := assemble(pf, sf, stdin)
+	if status != exitOK {
+		return status
+	}
+	stopRecording, err := rf.start(mach)
+	if err != nil {
+		return fail(exitError, "Error opening recording:", err)
+	}
+
+	// Let the user drive the machine. Since commands and input come from the
+	// same place, make it clear when the machine wants input.
+	if streams.stdin != nil && streams.socket == nil {
+		streams.stdin.Prompt = os.Stdout
+	}
+	newDebugger(mach, stdin, os.Stdout).run()
+	if err := stopRecording(); err != nil {
+		return fail(exitError, "Error writing recording:", err)
+	}
+
+	return finish(mach, streams)
+}
+
+func tuiCommand(cmd command, args []string) int {
+	fs := cmd.flagSet()
+	var pf projectFlags
+	pf.register(fs)
+	pf.registerStrict(fs)
+	var sf streamFlags
+	sf.register(fs)
+	utilization := registerUtilization(fs)
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		return badUsage(fs, "Unexpected argument '"+fs.Arg(0)+"'")
+	}
+
+	// The keyboard drives the view, so console input has to come from files
+	// and console output is only shown in the view
+	p, code, streams, status := openProject(pf, sf, nil, ioutil.Discard)
+	if status != exitOK {
+		return status
+	}
+	view := newTUI(p.config, code, &streams)
+	mach, status := build(p, code, streams)
+	if status != exitOK {
+		return status
+	}
+	view.mach = mach
+
+	term, err := openTerminal()
+	if err != nil {
+		return fail(exitError, "Error opening terminal:", err)
+	}
+	view.show(term)
+	if err := term.close(); err != nil {
+		return fail(exitError, "Error restoring terminal:", err)
+	}
+
+	fmt.Fprintln(os.Stderr, mach.Score())
+	if *utilization {
+		printUtilization(os.Stderr, mach)
+	}
+	return finish(mach, streams)
+}
+
+func serveCommand(cmd command, args []string) int {
+	fs := cmd.flagSet()
+	var pf projectFlags
+	pf.register(fs)
+	pf.registerStrict(fs)
+	var sf streamFlags
+	sf.register(fs)
+	addr := fs.String("addr", "localhost:8100", "listen for browsers on `ADDRESS`")
+	utilization := registerUtilization(fs)
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		return badUsage(fs, "Unexpected argument '"+fs.Arg(0)+"'")
+	}
+
+	// The browser drives the machine, which runs in the background, so
+	// console input has to come from files
+	p, code, streams, status := openProject(pf, sf, nil, os.Stdout)
+	if status != exitOK {
+		return status
+	}
+	srv := newServer(p.config, code, &streams)
+	mach, status := build(p, code, streams)
+	if status != exitOK {
+		return status
+	}
+	srv.mach = mach
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return fail(exitError, "Error starting server:", err)
+	}
+	httpServer := &http.Server{Handler: srv}
+	served := make(chan error, 1)
+	go func() {
+		served <- httpServer.Serve(ln)
+	}()
+	stop := make(chan struct{})
+	driven := make(chan struct{})
+	go func() {
+		srv.drive(stop)
+		close(driven)
+	}()
+	fmt.Fprintf(os.Stderr, "Serving on http://%v, press Ctrl+C to stop\n", ln.Addr())
+
+	// Serve until the user stops the program
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	select {
+	case <-interrupt:
+	case err := <-served:
+		close(stop)
+		return fail(exitError, "Error serving:", err)
+	}
+	close(stop)
+	<-driven
+	httpServer.Close()
+
+	fmt.Fprintln(os.Stderr, mach.Score())
+	if *utilization {
+		printUtilization(os.Stderr, mach)
+	}
+	return finish(mach, streams)
+}
+
+func importCommand(cmd command, args []string) int {
+	fs := cmd.flagSet()
+	var pf projectFlags
+	pf.register(fs)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return badUsage(fs, "Expected the save file to import")
+	}
+
+	p, err := pf.open()
+	if err != nil {
+		return fail(exitError, "Error parsing machine config:", err)
+	}
+
+	if err := p.importSolution(fs.Arg(0)); err != nil {
+		return fail(exitError, "Error importing "+fs.Arg(0)+":", err)
+	}
+
+	return exitOK
+}
+
+func exportCommand(cmd command, args []string) int {
+	fs := cmd.flagSet()
+	var pf projectFlags
+	pf.register(fs)
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		return badUsage(fs, "Unexpected argument '"+fs.Arg(1)+"'")
+	}
+
+	save := fs.Arg(0)
+	if save == "" {
+		save = "-"
+	}
+
+	p, err := pf.open()
+	if err != nil {
+		return fail(exitError, "Error parsing machine config:", err)
+	}
+
+	if err := p.exportSolution(save); err != nil {
+		return fail(exitError, "Error exporting "+save+":", err)
+	}
+
+	return exitOK
+}
+
+func helpCommand(cmd command, args []string) int {
+	if len(args) == 0 {
+		usage(os.Stdout)
+		return exitOK
+	}
+
+	other, ok := findCommand(args[0])
+	if !ok {
+		fmt.Fprintln(os.Stderr, "Unknown command '"+args[0]+"'")
+		usage(os.Stderr)
+		return exitUsage
+	}
+	if other.name == cmd.name {
+		cmd.flagSet().Usage()
+		return exitOK
+	}
+
+	// Every other command prints its usage and exits when asked for help
+	return other.run(other, []string{"-h"})
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage(os.Stderr)
+		os.Exit(exitUsage)
+	}
+
+	name := os.Args[1]
+	if name == "-h" || name == "-help" || name == "--help" {
+		usage(os.Stdout)
+		os.Exit(exitOK)
+	}
+
+	cmd, ok := findCommand(name)
+	if !ok {
+		fmt.Fprintln(os.Stderr, "Unknown command '"+name+"'")
+		usage(os.Stderr)
+		os.Exit(exitUsage)
+	}
 
-	<-mach.stopSignal
+	os.Exit(cmd.run(cmd, os.Args[2:]))
 }
@@ -0,0 +1,252 @@
+package tis
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// InputStream is a source of numbers for console input.
+type InputStream interface {
+	// Next returns the next number in the stream, or false once the stream has
+	// run out.
+	Next() (Number, bool)
+}
+
+// OutputStream is a destination for numbers from console output.
+type OutputStream interface {
+	Put(Number)
+}
+
+// consoleIn is a port that reads numbers from an input stream. Like a node
+// writing to a port, it offers one number at a time, and only reads the next
+// number from the stream once the one before it has been taken.
+type consoleIn struct {
+	name    string
+	stream  InputStream
+	offered *transfer // The number on offer, if there is one
+	done    bool
+	starved bool   // Whether a node asked for a number after the input ran out
+	read    int    // How many numbers have been taken by nodes
+	last    Number // The number taken last
+}
+
+// newConsoleIn creates a new console input with the given name that reads from
+// the given stream.
+func newConsoleIn(name string, stream InputStream) *consoleIn {
+	return &consoleIn{
+		name:   name,
+		stream: stream}
+}
+
+func (cin *consoleIn) String() string {
+	return cin.name
+}
+
+// empty returns true if the input has nothing on offer but its stream may
+// still have more numbers.
+func (cin *consoleIn) empty() bool {
+	return !cin.done && (cin.offered == nil || cin.offered.taken)
+}
+
+// offer offers a number read from the stream, or marks the input as done if
+// the stream has run out. The number is published right away, like it was
+// written in an earlier cycle, so it can be read this cycle.
+func (cin *consoleIn) offer(n Number, ok bool) {
+	if !ok {
+		cin.done = true
+		cin.offered = nil
+		return
+	}
+
+	cin.offered = newTransfer(n)
+	cin.offered.published = true
+}
+
+// readNum takes the number on offer, if there is one. Once the input is
+// exhausted, no more numbers are available.
+func (cin *consoleIn) readNum() (Number, bool) {
+	t := cin.offered
+	if !t.available() {
+		if cin.done {
+			cin.starved = true
+		}
+		return 0, false
+	}
+
+	t.taken = true
+	t.from = cin
+	cin.read++
+	cin.last = t.n
+	return t.n, true
+}
+
+// writeNum does nothing, as nothing on the other side of console input will
+// ever take a number.
+func (cin *consoleIn) writeNum(t *transfer) {}
+
+// consoleOut is a port that writes numbers to an output stream. Like a node
+// reading from a port, it only takes a number once it has been published, so
+// writing to it takes at least as long as writing to another node.
+type consoleOut struct {
+	name    string
+	stream  OutputStream
+	offered *transfer // The number a node is writing, if there is one
+	written int
+	last    Number // The number written last
+}
+
+// newConsoleOut creates a new console output with the given name that writes
+// to the given stream.
+func newConsoleOut(name string, stream OutputStream) *consoleOut {
+	return &consoleOut{
+		name:   name,
+		stream: stream}
+}
+
+func (cout *consoleOut) String() string {
+	return cout.name
+}
+
+// writeNum offers the transfer to the output. It is taken once it has been
+// published.
+func (cout *consoleOut) writeNum(t *transfer) {
+	cout.offered = t
+}
+
+// take writes the offered number to the stream if it has been published and
+// nothing else has taken it.
+func (cout *consoleOut) take() {
+	t := cout.offered
+	if !t.available() {
+		return
+	}
+
+	cout.stream.Put(t.n)
+	cout.written++
+	cout.last = t.n
+	t.taken = true
+	t.from = cout
+}
+
+// readNum never returns a number, as nothing is ever sent from console output.
+func (cout *consoleOut) readNum() (Number, bool) {
+	return 0, false
+}
+
+// TextInput is an input stream that reads numbers from text, one number per
+// line. Lines that aren't valid numbers are skipped.
+type TextInput struct {
+	in     *bufio.Reader
+	Prompt io.Writer // Where to ask for input, if anywhere
+	Name   string    // What to call the input when asking for it
+	Errors io.Writer // Where to report skipped lines and read failures, if anywhere
+}
+
+// NewTextInput creates a new text input stream that reads from the given
+// reader.
+func NewTextInput(r io.Reader) *TextInput {
+	return &TextInput{
+		in: bufio.NewReader(r)}
+}
+
+// Next reads lines until one holds a valid number or the text runs out.
+func (ti *TextInput) Next() (Number, bool) {
+	for {
+		if ti.Prompt != nil {
+			fmt.Fprint(ti.Prompt, ti.Name+"> ")
+		}
+
+		// Read a line of console input
+		input, err := ti.in.ReadString('\n')
+		if err != nil && err != io.EOF {
+			ti.report("Failure to read input:", err)
+			return 0, false
+		}
+		if err == io.EOF && input == "" {
+			return 0, false
+		}
+		input = strings.TrimSpace(input)
+		if input == "" {
+			continue
+		}
+
+		// Convert the input to an integer
+		inputInt, err := strconv.Atoi(input)
+		if err != nil {
+			ti.report("Invalid integer value:", input)
+			continue
+		}
+
+		// Convert the integer to a number
+		inputNum := NewNumber(inputInt)
+		if inputInt != int(inputNum) {
+			ti.report("Given integer is outside TIS-100 number bounds:", input)
+			continue
+		}
+
+		return inputNum, true
+	}
+}
+
+// report writes a problem with the input to Errors, if it's set.
+func (ti *TextInput) report(a ...interface{}) {
+	if ti.Errors != nil {
+		fmt.Fprintln(ti.Errors, a...)
+	}
+}
+
+// TextOutput is an output stream that writes numbers as text, one number per
+// line.
+type TextOutput struct {
+	out    io.Writer
+	Prefix string // Written in front of each number, if not empty
+}
+
+// NewTextOutput creates a new text output stream that writes to the given
+// writer.
+func NewTextOutput(w io.Writer) *TextOutput {
+	return &TextOutput{
+		out: w}
+}
+
+func (to *TextOutput) Put(n Number) {
+	if to.Prefix != "" {
+		fmt.Fprintln(to.out, to.Prefix+":", n)
+	} else {
+		fmt.Fprintln(to.out, n)
+	}
+}
+
+// SliceInput is an input stream that reads from a fixed list of numbers.
+type SliceInput struct {
+	values []Number
+}
+
+// NewSliceInput creates a new input stream that reads the given numbers in
+// order.
+func NewSliceInput(values []Number) *SliceInput {
+	return &SliceInput{
+		values: values}
+}
+
+func (si *SliceInput) Next() (Number, bool) {
+	if len(si.values) == 0 {
+		return 0, false
+	}
+
+	n := si.values[0]
+	si.values = si.values[1:]
+	return n, true
+}
+
+// SliceOutput is an output stream that collects numbers in a list.
+type SliceOutput struct {
+	Values []Number
+}
+
+func (so *SliceOutput) Put(n Number) {
+	so.Values = append(so.Values, n)
+}
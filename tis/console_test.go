@@ -0,0 +1,32 @@
+package tis
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestTextInput tests that text input reads one number per line, and skips
+// lines that aren't valid numbers after reporting them.
+func TestTextInput(t *testing.T) {
+	var errs bytes.Buffer
+	in := NewTextInput(strings.NewReader("1\n\nfoo\n 2 \n1000\n-3"))
+	in.Errors = &errs
+
+	var values []Number
+	for {
+		n, ok := in.Next()
+		if !ok {
+			break
+		}
+		values = append(values, n)
+	}
+
+	if len(values) != 3 || values[0] != 1 || values[1] != 2 || values[2] != -3 {
+		t.Error("expected 1, 2 and -3, got", values)
+	}
+	expected := "Invalid integer value: foo\nGiven integer is outside TIS-100 number bounds: 1000\n"
+	if errs.String() != expected {
+		t.Errorf("expected the skipped lines to be reported as %q, got %q", expected, errs.String())
+	}
+}
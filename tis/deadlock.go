@@ -0,0 +1,84 @@
+package tis
+
+import (
+	"fmt"
+	"strings"
+)
+
+// deadlockError is returned when a machine can't make any more progress
+// because its nodes are waiting on ports that will never be ready.
+type deadlockError struct {
+	cycle   int
+	blocked []*executionNode
+}
+
+func (e deadlockError) Error() string {
+	lines := []string{fmt.Sprint("deadlock on cycle ", e.cycle, ": no node can make progress")}
+	for _, en := range e.blocked {
+		ins := en.instructions[en.ip].base()
+		lines = append(lines, fmt.Sprint("  ", en, " is ", en.waitStatus(), " on line ", ins.line+1, ": ", ins.text))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Deadlock returns an error if no node made progress during the last
+// cycle and at least one node is stuck waiting on a port. Nodes waiting for
+// console input after it has run out don't count as a deadlock, since that is
+// how a program normally finishes.
+func (m *Machine) Deadlock() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.stalled || m.haltedBy != nil {
+		return nil
+	}
+	for _, cin := range m.inputs {
+		if cin.starved {
+			return nil
+		}
+	}
+
+	var blocked []*executionNode
+	for _, row := range m.nodes {
+		for _, elem := range row {
+			if en, ok := elem.(*executionNode); ok && en.waitingOn != nil {
+				blocked = append(blocked, en)
+			}
+		}
+	}
+
+	if len(blocked) == 0 {
+		return nil
+	}
+
+	return deadlockError{
+		cycle:   m.cycle,
+		blocked: blocked}
+}
+
+// haltError is returned when a node stopped the machine by running HCF.
+type haltError struct {
+	cycle int
+	node  *executionNode
+}
+
+func (e haltError) Error() string {
+	ins := e.node.instructions[e.node.ip].base()
+	return fmt.Sprint("node ", e.node, " halted the machine on cycle ", e.cycle, " with ", ins.text, " on line ", ins.line+1)
+}
+
+// Halted returns an error naming the node that stopped the machine if one ran
+// HCF.
+func (m *Machine) Halted() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.haltedBy == nil {
+		return nil
+	}
+
+	return haltError{
+		cycle: m.cycle,
+		node:  m.haltedBy}
+}
@@ -0,0 +1,408 @@
+package tis
+
+import (
+	"sort"
+	"strings"
+)
+
+type executionNode struct {
+	up, down, left, right, last port
+	any                         *anyPort
+	acc, bak                    *register
+
+	labels       map[string]int
+	instructions []instruction
+
+	name string
+
+	ip        int         // Position of the current instruction
+	pending   *transfer   // A written number that hasn't been taken yet
+	waitingOn interface{} // The port the current instruction is waiting on, if any
+	executed  int         // How many instructions have finished
+	onFire    bool        // Whether the node ran HCF, which stops the machine
+	modes     ModeCycles  // How many cycles the node spent in each mode
+
+	// Tracing is off unless tracer is set. The current instruction's port
+	// transfers are kept until it finishes so they can be traced.
+	tracer      *tracer
+	tracedRead  *PortTransfer
+	tracedWrite *PortTransfer
+}
+
+func newExecutionNode(name string, up, down, left, right port) *executionNode {
+	any := newAnyPort(up, down, left, right)
+
+	return &executionNode{
+		name:         name,
+		up:           up,
+		down:         down,
+		left:         left,
+		right:        right,
+		last:         newLastPort(any),
+		any:          any,
+		acc:          newRegister(0),
+		bak:          newRegister(0),
+		labels:       make(map[string]int),
+		instructions: make([]instruction, 0)}
+}
+
+func (en *executionNode) String() string {
+	return en.name
+}
+
+// load scans, lexes and parses the given source code into the node's
+// instructions. If strict is true, code that wouldn't fit in a node in the game
+// is rejected. Every problem found in the code is returned as ParseErrors, and
+// the node is left empty if there are any. Any code loaded before is replaced,
+// and the node starts over.
+func (en *executionNode) load(code string, strict bool) error {
+	en.reset()
+
+	var errs ParseErrors
+	addErrs := func(err error) {
+		if err != nil {
+			errs = append(errs, err.(ParseErrors)...)
+		}
+	}
+
+	// Create a scanner from the code
+	scan := newScanner()
+	scan.add(code)
+	if strict {
+		addErrs(scan.checkGameLimits())
+	}
+	scan.add("\n") // Add a newline to the end of the code in case one isn't there
+
+	// Lex tokens out of the code. Lines the lexer couldn't handle are left
+	// out, so the parser can still look for problems in the rest.
+	lex := newLexer(scan)
+	addErrs(lex.lex())
+
+	// Parse the tokens
+	parse := newParser(lex)
+	addErrs(parse.parse(en))
+
+	if len(errs) > 0 {
+		// Point each error at its line of code, in the order they appear
+		lines := strings.Split(code, "\n")
+		sort.SliceStable(errs, func(i, j int) bool {
+			if errs[i].Line != errs[j].Line {
+				return errs[i].Line < errs[j].Line
+			}
+			return errs[i].Column < errs[j].Column
+		})
+		for _, err := range errs {
+			err.Node = en.name
+			if err.Line <= len(lines) {
+				err.Source = strings.TrimRight(lines[err.Line-1], "\r")
+			}
+		}
+
+		en.labels = make(map[string]int)
+		en.instructions = nil
+	}
+
+	return errs.orNil()
+}
+
+// reset empties the node and puts it back the way it was before it ran. A
+// number it was still waiting to write is taken back.
+func (en *executionNode) reset() {
+	for _, p := range []port{en.up, en.down, en.left, en.right} {
+		switch p := p.(type) {
+		case *nodePort:
+			if en.pending != nil && p.offered == en.pending {
+				p.offered = nil
+			}
+		case *consoleOut:
+			if en.pending != nil && p.offered == en.pending {
+				p.offered = nil
+			}
+		}
+	}
+	en.any.pending = nil
+	en.any.lastUsedPort = nil
+
+	en.labels = make(map[string]int)
+	en.instructions = make([]instruction, 0)
+	en.ip = 0
+	en.acc.value = 0
+	en.bak.value = 0
+	en.pending = nil
+	en.waitingOn = nil
+	en.executed = 0
+	en.onFire = false
+	en.modes = ModeCycles{}
+	en.tracedRead, en.tracedWrite = nil, nil
+}
+
+// step runs the current instruction for one cycle. If the instruction is
+// waiting on a port, the node stays on it until the port is ready and no
+// progress is made.
+func (en *executionNode) step() bool {
+	// Don't run if the execution node is empty, or if it's waiting for a
+	// written number to be taken
+	if len(en.instructions) == 0 || en.pending != nil {
+		return false
+	}
+
+	en.waitingOn = nil
+	ip := en.ip
+
+	switch ins := en.instructions[en.ip].(type) {
+	case *nop:
+		// Do nothing
+		en.ip++
+	case *mov:
+		// Move data from the source into the destination
+		n, ok := en.read(ins.source)
+		if !ok {
+			return false
+		}
+		if !en.write(ins.dest, n) {
+			// The read still counts as progress
+			return true
+		}
+		en.ip++
+	case *swp:
+		// Swap what's in ACC with BAK
+		en.acc.value, en.bak.value = en.bak.value, en.acc.value
+		en.ip++
+	case *sav:
+		// Save the content of ACC to BAK
+		en.bak.value = en.acc.value
+		en.ip++
+	case *add:
+		// Add source to ACC
+		n, ok := en.read(ins.source)
+		if !ok {
+			return false
+		}
+		en.acc.value = addNum(en.acc.value, int(n))
+		en.ip++
+	case *sub:
+		// Sub source from ACC
+		n, ok := en.read(ins.source)
+		if !ok {
+			return false
+		}
+		en.acc.value = subtractNum(en.acc.value, int(n))
+		en.ip++
+	case *neg:
+		// Negate ACC
+		en.acc.value = NewNumber(-int(en.acc.value))
+		en.ip++
+	case *jmp:
+		// Jump execution to the given label
+		en.ip = ins.target
+	case *jez:
+		// Jump execution to the given label if ACC is zero
+		if en.acc.value == 0 {
+			en.ip = ins.target
+		} else {
+			en.ip++
+		}
+	case *jnz:
+		// Jump execution to the given label if ACC is not zero
+		if en.acc.value != 0 {
+			en.ip = ins.target
+		} else {
+			en.ip++
+		}
+	case *jgz:
+		// Jump execution to the given label if ACC is greater than zero
+		if en.acc.value > 0 {
+			en.ip = ins.target
+		} else {
+			en.ip++
+		}
+	case *jlz:
+		// Jump execution to the given label if ACC is less than zero
+		if en.acc.value < 0 {
+			en.ip = ins.target
+		} else {
+			en.ip++
+		}
+	case *jro:
+		// Move execution by the given offset unconditionally. Like in the
+		// game, jumping past either end stops at the first or last
+		// instruction instead of wrapping around, so JRO 0 runs itself
+		// forever.
+		n, ok := en.read(ins.source)
+		if !ok {
+			return false
+		}
+		en.ip += int(n)
+		if en.ip < 0 {
+			en.ip = 0
+		} else if en.ip >= len(en.instructions) {
+			en.ip = len(en.instructions) - 1
+		}
+	case *hcf:
+		// Halt and catch fire. The machine stops at the end of the cycle, so
+		// the node stays on the instruction.
+		en.onFire = true
+		en.finished(ip)
+		return true
+	default:
+		panic("unimplemented instruction")
+	}
+
+	// Wrap execution around to the beginning if need be
+	en.ip = en.ip % len(en.instructions)
+	en.finished(ip)
+
+	return true
+}
+
+// commit finishes the node's cycle. If a number the node wrote has been taken,
+// the instruction that wrote it is finished. Otherwise, the number is published
+// so that it can be read starting next cycle.
+func (en *executionNode) commit() bool {
+	if en.pending == nil {
+		return false
+	}
+
+	if en.pending.taken {
+		ip := en.ip
+		en.pending = nil
+		en.waitingOn = nil
+		en.ip = (en.ip + 1) % len(en.instructions)
+		en.finished(ip)
+		return true
+	}
+
+	published := en.pending.published
+	en.pending.published = true
+	return !published
+}
+
+// finished counts the instruction at the given index as finished, once the
+// node has moved on from it.
+func (en *executionNode) finished(ip int) {
+	en.executed++
+
+	if en.tracer != nil {
+		en.tracer.record(en, ip, en.resolveTransfer(en.tracedRead), en.resolveTransfer(en.tracedWrite))
+		en.tracedRead, en.tracedWrite = nil, nil
+	}
+}
+
+// read reads a number from the given source. If none is available, the node
+// waits on the source.
+func (en *executionNode) read(src numberReader) (Number, bool) {
+	n, ok := src.readNum()
+	if !ok {
+		en.waitingOn = src
+	}
+
+	if ok && en.tracer != nil && en.portName(src) != "" {
+		en.tracedRead = &PortTransfer{Port: en.portName(src), Value: n}
+	}
+
+	return n, ok
+}
+
+// write writes the number to the given destination and returns true if it was
+// taken right away. Otherwise, the node waits for it to be taken.
+func (en *executionNode) write(dest numberWriter, n Number) bool {
+	if en.tracer != nil && en.portName(dest) != "" {
+		en.tracedWrite = &PortTransfer{Port: en.portName(dest), Value: n}
+	}
+
+	t := newTransfer(n)
+	dest.writeNum(t)
+	if !t.taken {
+		en.pending = t
+		en.waitingOn = dest
+		return false
+	}
+
+	return true
+}
+
+// mode returns what the node spent its last cycle doing.
+func (en *executionNode) mode() Mode {
+	switch {
+	case len(en.instructions) == 0:
+		return ModeIdle
+	case en.waitingOn == nil:
+		return ModeRun
+	case en.pending != nil:
+		return ModeWrite
+	}
+
+	return ModeRead
+}
+
+// countMode counts the cycle that just finished towards the node's mode.
+func (en *executionNode) countMode() {
+	en.modes[en.mode()]++
+}
+
+// waitStatus describes what the node is waiting on, or returns an empty string
+// if it isn't waiting on anything.
+func (en *executionNode) waitStatus() string {
+	if en.waitingOn == nil {
+		return ""
+	}
+	if en.pending != nil {
+		return "waiting to write " + en.portName(en.waitingOn)
+	}
+
+	return "waiting to read " + en.portName(en.waitingOn)
+}
+
+// portName returns the name code uses to refer to the given port or
+// pseudo-port of the node, or an empty string if it isn't one of them.
+func (en *executionNode) portName(p interface{}) string {
+	switch p {
+	case en.up:
+		return "UP"
+	case en.down:
+		return "DOWN"
+	case en.left:
+		return "LEFT"
+	case en.right:
+		return "RIGHT"
+	case en.any:
+		return "ANY"
+	case en.last:
+		return "LAST"
+	}
+
+	return ""
+}
+
+// resolveTransfer resolves a transfer through ANY or LAST to the port the
+// number actually moved through, which is only known once the instruction has
+// finished. Nil is returned if there was no transfer, or if it went through
+// LAST before ANY was ever used.
+func (en *executionNode) resolveTransfer(pt *PortTransfer) *PortTransfer {
+	if pt == nil || (pt.Port != "ANY" && pt.Port != "LAST") {
+		return pt
+	}
+
+	last := en.any.last()
+	if last == nil {
+		return nil
+	}
+	pt.Port = en.portName(last)
+	return pt
+}
+
+func (en *executionNode) getUp() port {
+	return en.up
+}
+
+func (en *executionNode) getDown() port {
+	return en.down
+}
+
+func (en *executionNode) getLeft() port {
+	return en.left
+}
+
+func (en *executionNode) getRight() port {
+	return en.right
+}
@@ -0,0 +1,213 @@
+package tis
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	imageDefaultWidth  = 30
+	imageDefaultHeight = 18
+
+	// imageScale is how many pixels wide and tall each of the display's pixels
+	// are when saved as a PNG.
+	imageScale = 8
+
+	// imageRefresh is how often displays are redrawn in the terminal while the
+	// machine runs.
+	imageRefresh = 50 * time.Millisecond
+)
+
+// imagePalette holds the colors the display can show, indexed by the number
+// used to draw them. Any other number is drawn as black.
+var imagePalette = color.Palette{
+	color.RGBA{0x00, 0x00, 0x00, 0xff}, // Black
+	color.RGBA{0x46, 0x46, 0x46, 0xff}, // Dark grey
+	color.RGBA{0x9c, 0x9c, 0x9c, 0xff}, // Light grey
+	color.RGBA{0xfb, 0xfb, 0xfb, 0xff}, // White
+	color.RGBA{0xbf, 0x0a, 0x0a, 0xff}} // Red
+
+// imageANSIColors holds the 256-color terminal equivalent of each color in the
+// palette.
+var imageANSIColors = []int{16, 238, 248, 231, 124}
+
+// imageState represents what the image output expects the next number to be.
+type imageState int
+
+const (
+	imageStateX imageState = iota
+	imageStateY
+	imageStateColor
+)
+
+// ImageOutput is an output stream that draws to a display like the game's
+// visualization module. Numbers are read as an X position, a Y position, and
+// then a run of colors drawn left to right starting at that position. A
+// negative number ends the run, and the next number is a new X position.
+// Pixels that fall outside of the display are ignored.
+type ImageOutput struct {
+	width, height int
+	pixels        []Number
+
+	state imageState
+	x, y  int
+	dirty bool // Whether the display has changed since it was last rendered
+
+	mu sync.Mutex
+}
+
+// NewImageOutput creates a new display of the given size that starts out
+// black.
+func NewImageOutput(width, height int) *ImageOutput {
+	return &ImageOutput{
+		width:  width,
+		height: height,
+		pixels: make([]Number, width*height)}
+}
+
+// Put draws the number according to where it falls in the protocol.
+func (iout *ImageOutput) Put(n Number) {
+	iout.mu.Lock()
+	defer iout.mu.Unlock()
+
+	if n < 0 {
+		iout.state = imageStateX
+		return
+	}
+
+	switch iout.state {
+	case imageStateX:
+		iout.x = int(n)
+		iout.state = imageStateY
+	case imageStateY:
+		iout.y = int(n)
+		iout.state = imageStateColor
+	case imageStateColor:
+		if iout.x < iout.width && iout.y < iout.height {
+			iout.pixels[iout.y*iout.width+iout.x] = n
+			iout.dirty = true
+		}
+		iout.x++
+	}
+}
+
+// at returns the palette index of the pixel at the given position.
+func (iout *ImageOutput) at(x, y int) int {
+	c := int(iout.pixels[y*iout.width+x])
+	if c >= len(imagePalette) {
+		return 0
+	}
+
+	return c
+}
+
+// Image returns the current contents of the display with one image pixel per
+// display pixel.
+func (iout *ImageOutput) Image() *image.Paletted {
+	iout.mu.Lock()
+	defer iout.mu.Unlock()
+
+	img := image.NewPaletted(image.Rect(0, 0, iout.width, iout.height), imagePalette)
+	for y := 0; y < iout.height; y++ {
+		for x := 0; x < iout.width; x++ {
+			img.SetColorIndex(x, y, uint8(iout.at(x, y)))
+		}
+	}
+
+	return img
+}
+
+// WritePNG writes the current contents of the display as a PNG, scaled up so
+// that it can be seen.
+func (iout *ImageOutput) WritePNG(w io.Writer) error {
+	small := iout.Image()
+
+	img := image.NewPaletted(image.Rect(0, 0, iout.width*imageScale, iout.height*imageScale), imagePalette)
+	for y := 0; y < img.Rect.Dy(); y++ {
+		for x := 0; x < img.Rect.Dx(); x++ {
+			img.SetColorIndex(x, y, small.ColorIndexAt(x/imageScale, y/imageScale))
+		}
+	}
+
+	return png.Encode(w, img)
+}
+
+// Changed returns true if the display has been drawn to since it was last
+// rendered to a terminal.
+func (iout *ImageOutput) Changed() bool {
+	iout.mu.Lock()
+	defer iout.mu.Unlock()
+
+	return iout.dirty
+}
+
+// RenderANSI writes the current contents of the display for a terminal, using
+// half blocks so that each line of text holds two rows of pixels. It returns
+// the number of lines written.
+func (iout *ImageOutput) RenderANSI(w io.Writer) int {
+	iout.mu.Lock()
+	defer iout.mu.Unlock()
+
+	var buf bytes.Buffer
+	lines := 0
+	for y := 0; y < iout.height; y += 2 {
+		for x := 0; x < iout.width; x++ {
+			// The top pixel is the foreground and the bottom pixel is the
+			// background
+			top := imageANSIColors[iout.at(x, y)]
+			bottom := imageANSIColors[0]
+			if y+1 < iout.height {
+				bottom = imageANSIColors[iout.at(x, y+1)]
+			}
+			fmt.Fprintf(&buf, "\x1b[38;5;%dm\x1b[48;5;%dm▀", top, bottom)
+		}
+		buf.WriteString("\x1b[0m\n")
+		lines++
+	}
+	iout.dirty = false
+
+	w.Write(buf.Bytes())
+	return lines
+}
+
+// RenderLive draws the given displays to a terminal every time they change,
+// redrawing over the last frame instead of below it, until done is closed.
+// The final frame is always drawn before returning.
+func RenderLive(w io.Writer, images []*ImageOutput, done <-chan struct{}) {
+	ticker := time.NewTicker(imageRefresh)
+	defer ticker.Stop()
+
+	lines := 0
+	draw := func() {
+		if lines > 0 {
+			// Move back up to the top of the last frame
+			fmt.Fprintf(w, "\x1b[%dA", lines)
+		}
+		lines = 0
+		for _, iout := range images {
+			lines += iout.RenderANSI(w)
+		}
+	}
+
+	draw()
+	for {
+		select {
+		case <-done:
+			draw()
+			return
+		case <-ticker.C:
+			for _, iout := range images {
+				if iout.Changed() {
+					draw()
+					break
+				}
+			}
+		}
+	}
+}
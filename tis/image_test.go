@@ -0,0 +1,107 @@
+package tis
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+)
+
+// drawImage draws the given numbers to a new display of the given size.
+func drawImage(width, height int, values ...int) *ImageOutput {
+	iout := NewImageOutput(width, height)
+	for _, v := range values {
+		iout.Put(NewNumber(v))
+	}
+
+	return iout
+}
+
+func TestImageDrawsRuns(t *testing.T) {
+	iout := drawImage(4, 4,
+		1, 2, 3, 4, -1,
+		0, 0, 1, -1)
+
+	img := iout.Image()
+	expected := map[[2]int]uint8{
+		{1, 2}: 3,
+		{2, 2}: 4,
+		{0, 0}: 1}
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			if c := img.ColorIndexAt(x, y); c != expected[[2]int{x, y}] {
+				t.Errorf("expected pixel (%v, %v) to be %v, got %v", x, y, expected[[2]int{x, y}], c)
+			}
+		}
+	}
+}
+
+func TestImageIgnoresOffscreenPixels(t *testing.T) {
+	// The run goes off the right side and the second one starts below the
+	// display
+	iout := drawImage(2, 2,
+		1, 0, 3, 3, 3, -1,
+		0, 5, 3, -1)
+
+	img := iout.Image()
+	if c := img.ColorIndexAt(1, 0); c != 3 {
+		t.Errorf("expected pixel (1, 0) to be 3, got %v", c)
+	}
+	for _, p := range [][2]int{{0, 0}, {0, 1}, {1, 1}} {
+		if c := img.ColorIndexAt(p[0], p[1]); c != 0 {
+			t.Errorf("expected pixel %v to be 0, got %v", p, c)
+		}
+	}
+}
+
+func TestImageDrawsUnknownColorsBlack(t *testing.T) {
+	iout := drawImage(2, 1, 0, 0, 2, 9, -1)
+
+	img := iout.Image()
+	if c := img.ColorIndexAt(0, 0); c != 2 {
+		t.Errorf("expected pixel (0, 0) to be 2, got %v", c)
+	}
+	if c := img.ColorIndexAt(1, 0); c != 0 {
+		t.Errorf("expected pixel (1, 0) to be 0, got %v", c)
+	}
+}
+
+func TestImageWritesScaledPNG(t *testing.T) {
+	iout := drawImage(3, 2, 2, 1, 4, -1)
+
+	var buf bytes.Buffer
+	if err := iout.WritePNG(&buf); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size := img.Bounds().Size(); size.X != 3*imageScale || size.Y != 2*imageScale {
+		t.Fatalf("expected a %vx%v image, got %v", 3*imageScale, 2*imageScale, size)
+	}
+	if c := img.At(2*imageScale+1, imageScale+1); c != imagePalette[4] {
+		t.Errorf("expected the drawn pixel to be red, got %v", c)
+	}
+	if c := img.At(0, 0); c != imagePalette[0] {
+		t.Errorf("expected an undrawn pixel to be black, got %v", c)
+	}
+}
+
+func TestImageRendersANSI(t *testing.T) {
+	iout := drawImage(2, 3, 0, 0, 3, -1)
+	if !iout.Changed() {
+		t.Error("expected the display to be changed after drawing")
+	}
+
+	var buf bytes.Buffer
+	if lines := iout.RenderANSI(&buf); lines != 2 {
+		t.Errorf("expected 3 rows to take 2 lines, took %v", lines)
+	}
+	if iout.Changed() {
+		t.Error("expected the display to be unchanged after rendering")
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("\x1b[38;5;231m\x1b[48;5;16m▀")) {
+		t.Errorf("expected the first pixel to be white over black, got %q", buf.String())
+	}
+}
@@ -0,0 +1,213 @@
+package tis
+
+import "errors"
+
+// operandKind is the kind of operand an instruction takes in one of its
+// places.
+type operandKind int
+
+const (
+	operandSource operandKind = iota // A number, or a register or port to read from
+	operandDest                      // A register or port to write to
+	operandLabel                     // A label to jump to
+)
+
+// instructionSpec describes an instruction by the operands it takes, in order,
+// and how to create an empty one to build.
+type instructionSpec struct {
+	operands []operandKind
+	create   func() instruction
+}
+
+// instructionSpecs holds the spec of every instruction, keyed by name.
+var instructionSpecs = map[string]instructionSpec{
+	"NOP": {nil, func() instruction { return &nop{} }},
+	"MOV": {[]operandKind{operandSource, operandDest}, func() instruction { return &mov{} }},
+	"SWP": {nil, func() instruction { return &swp{} }},
+	"SAV": {nil, func() instruction { return &sav{} }},
+	"ADD": {[]operandKind{operandSource}, func() instruction { return &add{} }},
+	"SUB": {[]operandKind{operandSource}, func() instruction { return &sub{} }},
+	"NEG": {nil, func() instruction { return &neg{} }},
+	"JMP": {[]operandKind{operandLabel}, func() instruction { return &jmp{} }},
+	"JEZ": {[]operandKind{operandLabel}, func() instruction { return &jez{} }},
+	"JNZ": {[]operandKind{operandLabel}, func() instruction { return &jnz{} }},
+	"JGZ": {[]operandKind{operandLabel}, func() instruction { return &jgz{} }},
+	"JLZ": {[]operandKind{operandLabel}, func() instruction { return &jlz{} }},
+	"JRO": {[]operandKind{operandSource}, func() instruction { return &jro{} }},
+	"HCF": {nil, func() instruction { return &hcf{} }}}
+
+// patternFromName returns an instruction pattern, which is the kind of each
+// operand the instruction takes, in order. If no known instruction with that
+// name exists, an error is returned.
+func patternFromName(insName string) ([]operandKind, error) {
+	spec, ok := instructionSpecs[insName]
+	if !ok {
+		return nil, errors.New("invalid instruction " + insName)
+	}
+
+	return spec.operands, nil
+}
+
+// instructionFromName returns an empty buildable instruction based on the
+// given instruction name, or an error if no such instruction is known.
+func instructionFromName(insName string) (instruction, error) {
+	spec, ok := instructionSpecs[insName]
+	if !ok {
+		return &nop{}, errors.New("invalid instruction " + insName)
+	}
+
+	return spec.create(), nil
+}
+
+// instruction is a single parsed instruction. Operands are checked against the
+// instruction's spec before they're given to setArg, so each one is already
+// the type the instruction expects in that place: a numberReader for sources,
+// a numberWriter for destinations and a string for labels.
+type instruction interface {
+	setArg(data interface{}, place int)
+	base() *instructionBase
+}
+
+// instructionBase holds what every instruction knows about itself, no matter
+// what kind of instruction it is.
+type instructionBase struct {
+	line       int    // The line of code the instruction is on, starting at 0
+	text       string // The instruction as it was parsed
+	breakpoint bool   // Whether a debugger should pause before the instruction
+}
+
+// base returns the instruction's common information.
+func (b *instructionBase) base() *instructionBase {
+	return b
+}
+
+// setArg does nothing, for instructions that take no operands.
+func (b *instructionBase) setArg(data interface{}, place int) {}
+
+// jumpTarget holds where a jump instruction goes. The label is resolved to the
+// index of an instruction once the whole node has been parsed.
+type jumpTarget struct {
+	l      string
+	target int
+}
+
+// jump returns the instruction's jump target.
+func (j *jumpTarget) jump() *jumpTarget {
+	return j
+}
+
+// jumpInstruction is an instruction that jumps to a label.
+type jumpInstruction interface {
+	instruction
+	jump() *jumpTarget
+}
+
+type nop struct {
+	instructionBase
+}
+
+type mov struct {
+	instructionBase
+
+	source numberReader
+	dest   numberWriter
+}
+
+func (movIns *mov) setArg(data interface{}, place int) {
+	if place == 0 {
+		movIns.source = data.(numberReader)
+	} else {
+		movIns.dest = data.(numberWriter)
+	}
+}
+
+type swp struct {
+	instructionBase
+}
+
+type sav struct {
+	instructionBase
+}
+
+type add struct {
+	instructionBase
+
+	source numberReader
+}
+
+func (addIns *add) setArg(data interface{}, place int) {
+	addIns.source = data.(numberReader)
+}
+
+type sub struct {
+	instructionBase
+
+	source numberReader
+}
+
+func (subIns *sub) setArg(data interface{}, place int) {
+	subIns.source = data.(numberReader)
+}
+
+type neg struct {
+	instructionBase
+}
+
+type jmp struct {
+	instructionBase
+	jumpTarget
+}
+
+func (jmpIns *jmp) setArg(data interface{}, place int) {
+	jmpIns.l = data.(string)
+}
+
+type jez struct {
+	instructionBase
+	jumpTarget
+}
+
+func (jezIns *jez) setArg(data interface{}, place int) {
+	jezIns.l = data.(string)
+}
+
+type jnz struct {
+	instructionBase
+	jumpTarget
+}
+
+func (jnzIns *jnz) setArg(data interface{}, place int) {
+	jnzIns.l = data.(string)
+}
+
+type jgz struct {
+	instructionBase
+	jumpTarget
+}
+
+func (jgzIns *jgz) setArg(data interface{}, place int) {
+	jgzIns.l = data.(string)
+}
+
+type jlz struct {
+	instructionBase
+	jumpTarget
+}
+
+func (jlzIns *jlz) setArg(data interface{}, place int) {
+	jlzIns.l = data.(string)
+}
+
+type jro struct {
+	instructionBase
+
+	source numberReader
+}
+
+func (jroIns *jro) setArg(data interface{}, place int) {
+	jroIns.source = data.(numberReader)
+}
+
+type hcf struct {
+	instructionBase
+}
@@ -1,4 +1,4 @@
-package main
+package tis
 
 import (
 	"strings"
@@ -10,10 +10,11 @@ type tokenType int
 
 //go:generate stringer -type=tokenType
 const (
-	_           tokenType = iota
-	tokenName             // Token is the name of something
-	tokenLabel            // Token is a label
-	tokenNumber           // Token is a number literal
+	_               tokenType = iota
+	tokenName                 // Token is the name of something
+	tokenLabel                // Token is a label
+	tokenNumber               // Token is a number literal
+	tokenBreakpoint           // Token is a breakpoint marker
 )
 
 // token is a single lexical token.
@@ -48,19 +49,40 @@ func newLexer(scan scanner) lexer {
 		tokens: make([]token, 0, 20)}
 }
 
-// lex starts lexing the input from the scanner object.
+// lex starts lexing the input from the scanner object. When a line has an
+// invalid character, it's left out and lexing continues on the next line, so
+// that every line with a problem is reported.
 func (l *lexer) lex() error {
 	var data string
 	var startingChar char
+	var errs ParseErrors
+	skipLine := -1
+
+	// fail records an error for the given character and throws out what's been
+	// lexed on its line
+	fail := func(message string, character char) {
+		errs = append(errs, newParseError(message, character))
+		for len(l.tokens) > 0 && l.tokens[len(l.tokens)-1].startingChar.line == character.line {
+			l.tokens = l.tokens[:len(l.tokens)-1]
+		}
+		data = ""
+		l.state = lexerStateNone
+		skipLine = character.line
+	}
 
 	// Loop through ever character
 	for character, hasNextChar := l.scan.next(); hasNextChar; character, hasNextChar = l.scan.next() {
+		if character.line == skipLine {
+			continue
+		}
+
 		switch l.state {
 		case lexerStateNone:
 			// The lexer is waiting for a new state
 
-			if unicode.IsLetter(character.c) {
-				// A letter can either mean a name of some kind or a label
+			if unicode.IsLetter(character.c) || character.c == '_' {
+				// A letter or underscore can either mean a name of some kind or
+				// a label
 				l.state = lexerStateNameOrLabel
 				startingChar = character
 				data += string(character.c)
@@ -69,15 +91,24 @@ func (l *lexer) lex() error {
 				l.state = lexerStateNumber
 				startingChar = character
 				data += string(character.c)
-			} else if !unicode.IsSpace(character.c) {
-				// If the character isn't a letter, number, or space, it isn't valid
-				return newParseError("unexpected character '"+string(character.c)+"'", character)
+			} else if character.c == '!' {
+				// An exclamation mark sets a breakpoint on the instruction
+				// that follows
+				l.tokens = append(l.tokens, token{
+					tType:        tokenBreakpoint,
+					startingChar: character,
+					data:         "!"})
+			} else if !isSeparator(character.c) {
+				// If the character isn't a letter, number, or separator, it
+				// isn't valid
+				fail("unexpected character '"+string(character.c)+"'", character)
 			}
 		case lexerStateNameOrLabel:
 			// The lexer expects more characters or a sign that the token is finished
 
-			if unicode.IsLetter(character.c) {
-				// Another letter, so the name or label is still being constructed
+			if isNameChar(character.c) {
+				// Another letter, digit or underscore, so the name or label is
+				// still being constructed
 				data += string(character.c)
 			} else if character.c == ':' {
 				// A colon denotes the end of a label
@@ -87,8 +118,8 @@ func (l *lexer) lex() error {
 					data:         strings.ToUpper(data)})
 				data = ""
 				l.state = lexerStateNone
-			} else if unicode.IsSpace(character.c) {
-				// A space denotes the end of a name
+			} else if isSeparator(character.c) {
+				// A space or comma denotes the end of a name
 				l.tokens = append(l.tokens, token{
 					tType:        tokenName,
 					startingChar: startingChar,
@@ -97,7 +128,7 @@ func (l *lexer) lex() error {
 				l.state = lexerStateNone // Reset the lexer state
 			} else {
 				// An invalid character
-				return newParseError("unexpected character '"+string(character.c)+"'", character)
+				fail("unexpected character '"+string(character.c)+"'", character)
 			}
 		case lexerStateNumber:
 			// The lexer expects more numbers or a sign that the token is finished
@@ -105,8 +136,8 @@ func (l *lexer) lex() error {
 			if unicode.IsDigit(character.c) {
 				// Another digit, so the number is still being constructed
 				data += string(character.c)
-			} else if unicode.IsSpace(character.c) {
-				// A space denotes the end of the number
+			} else if isSeparator(character.c) {
+				// A space or comma denotes the end of the number
 				l.tokens = append(l.tokens, token{
 					tType:        tokenNumber,
 					startingChar: startingChar,
@@ -115,12 +146,12 @@ func (l *lexer) lex() error {
 				l.state = lexerStateNone // Reset the lexer state
 			} else {
 				// An invalid character
-				return newParseError("unexpected character '"+string(character.c)+"'", character)
+				fail("unexpected character '"+string(character.c)+"'", character)
 			}
 		}
 	}
 
-	return nil
+	return errs.orNil()
 }
 
 // next returns the next parsed token, or an empty token and false if no more
@@ -133,3 +164,15 @@ func (l *lexer) next() (token, bool) {
 		return token{}, false
 	}
 }
+
+// isNameChar returns true if the character can be part of a name or label
+// after the first character.
+func isNameChar(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_'
+}
+
+// isSeparator returns true if the character separates tokens. Like in the
+// game, operands can be separated by commas as well as spaces.
+func isSeparator(c rune) bool {
+	return unicode.IsSpace(c) || c == ','
+}
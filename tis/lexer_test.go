@@ -1,4 +1,4 @@
-package main
+package tis
 
 import "testing"
 
@@ -235,3 +235,110 @@ func TestLexingInvalidSyntax(t *testing.T) {
 		t.Error("lexer didn't fail even though an invalid number was given")
 	}
 }
+
+// TestLexingBreakpoints tests the lexer's ability to emit breakpoint tokens
+// for the game's breakpoint marker, both on its own and directly in front of
+// an instruction.
+func TestLexingBreakpoints(t *testing.T) {
+	// Create a new scanner with the test data
+	scan := newScanner()
+	scan.add("!nop\n! add 1\n")
+
+	// Lex the data
+	lex := newLexer(scan)
+	err := lex.lex()
+	if err != nil {
+		t.Error(err)
+	}
+
+	// How the lexer should react for each token
+	testCases := []struct {
+		expected token
+		hasNext  bool
+	}{
+		{
+			hasNext: true,
+			expected: token{
+				tType:        tokenBreakpoint,
+				startingChar: char{c: '!', pos: 0, line: 0},
+				data:         "!"}},
+		{
+			hasNext: true,
+			expected: token{
+				tType:        tokenName,
+				startingChar: char{c: 'n', pos: 1, line: 0},
+				data:         "NOP"}},
+		{
+			hasNext: true,
+			expected: token{
+				tType:        tokenBreakpoint,
+				startingChar: char{c: '!', pos: 0, line: 1},
+				data:         "!"}},
+		{
+			hasNext: true,
+			expected: token{
+				tType:        tokenName,
+				startingChar: char{c: 'a', pos: 2, line: 1},
+				data:         "ADD"}},
+		{
+			hasNext: true,
+			expected: token{
+				tType:        tokenNumber,
+				startingChar: char{c: '1', pos: 6, line: 1},
+				data:         "1"}},
+		{
+			hasNext:  false,
+			expected: token{}}}
+
+	// Check against each test case
+	for _, testCase := range testCases {
+		// Get the next token
+		tok, hasNext := lex.next()
+		// Check if the lexer's token count is as expected
+		if hasNext != testCase.hasNext {
+			if hasNext {
+				t.Error("lexer has an unexpected extra token")
+			} else {
+				t.Error("lexer ran out of tokens prematurely")
+			}
+		}
+		// Check if the value of the emitted token is as expected
+		if tok != testCase.expected {
+			t.Error("expected the token", testCase.expected, "but got", tok)
+		}
+	}
+}
+
+// TestLexingGameSyntax tests the lexer on syntax the game accepts: commas
+// between operands, digits and underscores in labels, and a label directly
+// followed by an instruction.
+func TestLexingGameSyntax(t *testing.T) {
+	scan := newScanner()
+	scan.add("LOOP_2:MOV UP,ACC\nL1: ADD -1, ACC\n")
+
+	lex := newLexer(scan)
+	if err := lex.lex(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []token{
+		{tType: tokenLabel, startingChar: char{c: 'L', pos: 0, line: 0}, data: "LOOP_2"},
+		{tType: tokenName, startingChar: char{c: 'M', pos: 7, line: 0}, data: "MOV"},
+		{tType: tokenName, startingChar: char{c: 'U', pos: 11, line: 0}, data: "UP"},
+		{tType: tokenName, startingChar: char{c: 'A', pos: 14, line: 0}, data: "ACC"},
+		{tType: tokenLabel, startingChar: char{c: 'L', pos: 0, line: 1}, data: "L1"},
+		{tType: tokenName, startingChar: char{c: 'A', pos: 4, line: 1}, data: "ADD"},
+		{tType: tokenNumber, startingChar: char{c: '-', pos: 8, line: 1}, data: "-1"},
+		{tType: tokenName, startingChar: char{c: 'A', pos: 12, line: 1}, data: "ACC"}}
+
+	for _, tok := range expected {
+		if got, hasNext := lex.next(); !hasNext {
+			t.Fatal("lexer ran out of tokens prematurely")
+		} else if got != tok {
+			t.Error("expected the token", tok, "but got", got)
+		}
+	}
+	if _, hasNext := lex.next(); hasNext {
+		t.Error("lexer has an unexpected extra token")
+	}
+}
@@ -1,6 +1,6 @@
 // generated by stringer -type=lexerState; DO NOT EDIT
 
-package main
+package tis
 
 import "fmt"
 
@@ -0,0 +1,508 @@
+// Package tis is a virtual machine for the TIS-100 and an interpreter of its
+// assembly language. A machine is built from a Config, given code for each of
+// its execution nodes, and then run one cycle at a time or in the background.
+package tis
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+)
+
+// Config contains the configuration information of a single machine. This can
+// be used to construct a Machine.
+type Config struct {
+	Name    string         `json:"name"`
+	Nodes   [][]string     `json:"nodes"`
+	Inputs  []StreamConfig `json:"inputs"`
+	Outputs []StreamConfig `json:"outputs"`
+
+	// ConsoleIn and ConsoleOut are a shorthand for a single input named IN and
+	// a single output named OUT. They are moved into Inputs and Outputs when
+	// the config is parsed.
+	ConsoleIn  *StreamConfig `json:"consoleIn"`
+	ConsoleOut *StreamConfig `json:"consoleOut"`
+
+	// Strict limits the code in each node to what fits in the game, which is
+	// 15 lines of 18 characters.
+	Strict bool `json:"strict"`
+
+	// StackCapacity is how many numbers each stack node can hold. It defaults
+	// to 15, like in the game.
+	StackCapacity int `json:"stackCapacity"`
+}
+
+// StreamConfig describes where a console input or output plugs into the node
+// array. If it plugs into the top or bottom, the position is its x position.
+// If it plugs into the left or right, the position is its y position. Outputs
+// can also be images, which draw to a display of the given size.
+type StreamConfig struct {
+	Name   string `json:"name"`
+	Side   string `json:"side"`
+	Pos    int    `json:"pos"`
+	Type   string `json:"type"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// edge is a place on the outside of the node array where something can be
+// plugged in.
+type edge struct {
+	side string
+	pos  int
+}
+
+// LoadConfig creates a new machine configuration object based on the provided
+// config file location.
+func LoadConfig(config string) (Config, error) {
+	// Read the data from the config file
+	data, err := ioutil.ReadFile(config)
+	if err != nil {
+		return Config{}, err
+	}
+
+	return ParseConfig(data)
+}
+
+// ParseConfig creates a new machine configuration object from the given JSON
+// data.
+func ParseConfig(data []byte) (Config, error) {
+	var mc Config
+
+	// Interpret the data as JSON and populate the machine configuration object
+	err := json.Unmarshal(data, &mc)
+	if err != nil {
+		return Config{}, err
+	}
+
+	// Make sure the given nodes create a rectangle
+	if len(mc.Nodes) == 0 || len(mc.Nodes[0]) == 0 {
+		return Config{}, errors.New("node array must not be empty")
+	}
+	nodeWidth := len(mc.Nodes[0])
+	nodeHeight := len(mc.Nodes)
+	for _, val := range mc.Nodes {
+		if len(val) != nodeWidth {
+			return Config{}, errors.New("node array must form a rectangle")
+		}
+	}
+
+	// Move the shorthand console input and output in with the others
+	if mc.ConsoleIn != nil {
+		if mc.ConsoleIn.Name == "" {
+			mc.ConsoleIn.Name = "IN"
+		}
+		mc.Inputs = append([]StreamConfig{*mc.ConsoleIn}, mc.Inputs...)
+		mc.ConsoleIn = nil
+	}
+	if mc.ConsoleOut != nil {
+		if mc.ConsoleOut.Name == "" {
+			mc.ConsoleOut.Name = "OUT"
+		}
+		mc.Outputs = append([]StreamConfig{*mc.ConsoleOut}, mc.Outputs...)
+		mc.ConsoleOut = nil
+	}
+
+	// Fill in the defaults for each input and output, and check that only
+	// outputs are images
+	for i := range mc.Inputs {
+		if mc.Inputs[i].Type == "" {
+			mc.Inputs[i].Type = "console"
+		}
+		if mc.Inputs[i].Type != "console" {
+			return Config{}, errors.New(mc.Inputs[i].Name + " has an invalid type value")
+		}
+	}
+	for i := range mc.Outputs {
+		sc := &mc.Outputs[i]
+		switch sc.Type {
+		case "", "console":
+			sc.Type = "console"
+		case "image":
+			if sc.Width == 0 {
+				sc.Width = imageDefaultWidth
+			}
+			if sc.Height == 0 {
+				sc.Height = imageDefaultHeight
+			}
+			if sc.Width < 0 || sc.Height < 0 {
+				return Config{}, errors.New(sc.Name + " must have a positive width and height")
+			}
+		default:
+			return Config{}, errors.New(sc.Name + " has an invalid type value")
+		}
+	}
+
+	// Check that each input and output has a unique name and a valid place of
+	// its own
+	names := make(map[string]bool)
+	edges := make(map[edge]string)
+	for _, sc := range append(append([]StreamConfig{}, mc.Inputs...), mc.Outputs...) {
+		if sc.Name == "" {
+			return Config{}, errors.New("every input and output must have a name")
+		}
+		if names[sc.Name] {
+			return Config{}, errors.New("more than one input or output is named '" + sc.Name + "'")
+		}
+		names[sc.Name] = true
+
+		switch sc.Side {
+		case "top", "bottom":
+			if sc.Pos < 0 || sc.Pos >= nodeWidth {
+				return Config{}, errors.New(sc.Name + " pos must be within the width of the node array")
+			}
+		case "left", "right":
+			if sc.Pos < 0 || sc.Pos >= nodeHeight {
+				return Config{}, errors.New(sc.Name + " pos must be within the height of the node array")
+			}
+		default:
+			return Config{}, errors.New(sc.Name + " has an invalid side value")
+		}
+
+		e := edge{side: sc.Side, pos: sc.Pos}
+		if other, ok := edges[e]; ok {
+			return Config{}, errors.New(sc.Name + " is in the same place as " + other)
+		}
+		edges[e] = sc.Name
+	}
+
+	if mc.StackCapacity < 0 {
+		return Config{}, errors.New("stackCapacity must be positive")
+	}
+
+	return mc, nil
+}
+
+// ExecutionNodeNames returns the name of every execution node in the config,
+// from left to right and top to bottom. This is the same order the game
+// numbers its nodes in.
+func (config Config) ExecutionNodeNames() []string {
+	var names []string
+	for y, row := range config.Nodes {
+		for x, kind := range row {
+			if kind == "e" {
+				names = append(names, fmt.Sprint(x, "-", y))
+			}
+		}
+	}
+
+	return names
+}
+
+// stackCapacity returns how many numbers each stack node can hold, which is
+// the game's capacity unless the config says otherwise.
+func (config Config) stackCapacity() int {
+	if config.StackCapacity == 0 {
+		return defaultStackCapacity
+	}
+
+	return config.StackCapacity
+}
+
+// Machine represents the TIS-100 instance. It is a collection of nodes that
+// run in lockstep off of a single clock. A machine is safe to query while it
+// runs in the background.
+type Machine struct {
+	mu     sync.Mutex
+	stepMu sync.Mutex // Held for a whole cycle, so only one runs at a time
+
+	nodes [][]node
+	cycle int
+
+	stopRequest chan struct{}
+	stopSignal  chan struct{}
+	stopOnce    sync.Once
+	doneOnce    sync.Once
+
+	inputs  []*consoleIn
+	outputs []*consoleOut
+
+	outputCount int // How many numbers have been written to console outputs
+	outputCycle int // The cycle the last number was written to a console output on
+
+	stalled  bool           // Whether no node made progress during the last cycle
+	haltedBy *executionNode // The node that ran HCF, if one has
+	strict   bool           // Whether code is held to the game's limits
+	tracer   *tracer        // Where instructions are traced to, if anywhere
+	vcd      *vcdRecorder   // Where a waveform of the machine is recorded to, if anywhere
+}
+
+// NewMachine creates a new machine from the given machine config. It
+// creates empty nodes based on the configuration and wires them up to each
+// other. Each console input reads from the stream in inputs with the same name,
+// and each console output writes to the stream in outputs with the same name.
+func NewMachine(config Config, inputs map[string]InputStream, outputs map[string]OutputStream) (*Machine, error) {
+	var m Machine
+
+	m.stopRequest = make(chan struct{})
+	m.stopSignal = make(chan struct{})
+	m.strict = config.Strict
+
+	// Construct the console inputs and outputs, keeping track of where they
+	// plug into the node array
+	edges := make(map[edge]port)
+	for _, sc := range config.Inputs {
+		stream, ok := inputs[sc.Name]
+		if !ok {
+			return nil, errors.New("no stream was given for input " + sc.Name)
+		}
+
+		cin := newConsoleIn(sc.Name, stream)
+		m.inputs = append(m.inputs, cin)
+		edges[edge{side: sc.Side, pos: sc.Pos}] = cin
+	}
+	for _, sc := range config.Outputs {
+		stream, ok := outputs[sc.Name]
+		if !ok {
+			return nil, errors.New("no stream was given for output " + sc.Name)
+		}
+
+		cout := newConsoleOut(sc.Name, stream)
+		m.outputs = append(m.outputs, cout)
+		edges[edge{side: sc.Side, pos: sc.Pos}] = cout
+	}
+
+	// Construct an empty array of nodes based on the size of the nodes in the config
+	m.nodes = make([][]node, len(config.Nodes))
+	for i := range m.nodes {
+		m.nodes[i] = make([]node, len(config.Nodes[0]))
+	}
+
+	nodeWidth := len(m.nodes[0])
+	nodeHeight := len(m.nodes)
+
+	// Create the links between neighbouring nodes. The node on the left or on
+	// top gets one end of a link and the node on the right or on the bottom
+	// gets the other.
+	horizontal := make([][]*nodePort, nodeHeight)
+	vertical := make([][]*nodePort, nodeHeight)
+	for y := range m.nodes {
+		horizontal[y] = make([]*nodePort, nodeWidth)
+		vertical[y] = make([]*nodePort, nodeWidth)
+		for x := range m.nodes[y] {
+			horizontal[y][x] = newNodePort()
+			vertical[y][x] = newNodePort()
+		}
+	}
+
+	for y, valY := range config.Nodes {
+		for x, valX := range valY {
+			// The node's ports default to ones that go nowhere. The links below
+			// and to the right only go somewhere if there is a node there.
+			var up, down, left, right port = newNodePort(), vertical[y][x], newNodePort(), horizontal[y][x]
+
+			if y-1 >= 0 {
+				// If there is a node above this node, connect it
+				up = vertical[y-1][x].peer
+			}
+			if x-1 >= 0 {
+				// If there is a node to the left of this node, connect it
+				left = horizontal[y][x-1].peer
+			}
+
+			// See if any console inputs or outputs plug into this node
+			if p, ok := edges[edge{side: "top", pos: x}]; ok && y == 0 {
+				up = p
+			}
+			if p, ok := edges[edge{side: "bottom", pos: x}]; ok && y == nodeHeight-1 {
+				down = p
+			}
+			if p, ok := edges[edge{side: "left", pos: y}]; ok && x == 0 {
+				left = p
+			}
+			if p, ok := edges[edge{side: "right", pos: y}]; ok && x == nodeWidth-1 {
+				right = p
+			}
+
+			switch valX {
+			case "e":
+				// The node is an execution node
+				m.nodes[y][x] = newExecutionNode(fmt.Sprint(x, "-", y), up, down, left, right)
+			case "s":
+				// The node is a stack node
+				m.nodes[y][x] = newStackNode(fmt.Sprint(x, "-", y), config.stackCapacity(), up, down, left, right)
+			default:
+				// The node is invalid
+
+				return nil, errors.New("invalid node type '" + valX + "'")
+			}
+		}
+	}
+
+	return &m, nil
+}
+
+// Load loads code into the machine's execution nodes. The code for each node
+// is looked up by the node's name, like "1-0", and nodes without any code are
+// left empty. If the config is strict, code over the game's limits is an
+// error. Every node's code is checked, and if any of it has problems, all of
+// them are returned as ParseErrors and the machine shouldn't be run.
+func (m *Machine) Load(code map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var errs ParseErrors
+	for _, row := range m.nodes {
+		for _, elem := range row {
+			if en, ok := elem.(*executionNode); ok {
+				if err := en.load(code[en.name], m.strict); err != nil {
+					errs = append(errs, err.(ParseErrors)...)
+				}
+			}
+		}
+	}
+
+	return errs.orNil()
+}
+
+// Start starts the machine's clock in the background. See Run for when the
+// machine stops.
+func (m *Machine) Start() {
+	go m.Run()
+}
+
+// Run runs the machine until Stop is called, a node runs HCF, or a cycle
+// passes where no node makes any progress, after which the channel returned by
+// Done is closed. It's safe to call Run more than once, or after Start, since
+// cycles are stepped one at a time and Done is only closed once.
+func (m *Machine) Run() {
+	defer m.doneOnce.Do(func() {
+		close(m.stopSignal)
+	})
+
+	for {
+		select {
+		case <-m.stopRequest:
+			return
+		default:
+			if !m.Step() {
+				return
+			}
+		}
+	}
+}
+
+// Stop asks the machine to stop at the end of the current cycle.
+func (m *Machine) Stop() {
+	m.stopOnce.Do(func() {
+		close(m.stopRequest)
+	})
+}
+
+// Done returns a channel that is closed once the machine stops running.
+func (m *Machine) Done() <-chan struct{} {
+	return m.stopSignal
+}
+
+// Cycle returns how many cycles the machine has run for.
+func (m *Machine) Cycle() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.cycle
+}
+
+// Step advances the whole machine by one cycle. It returns true if any node
+// made progress. Once a node runs HCF, the machine stops for good at the end
+// of the cycle and Step returns false. If a console input's stream blocks
+// while Step waits for its next number, the machine can still be queried.
+func (m *Machine) Step() bool {
+	m.stepMu.Lock()
+	defer m.stepMu.Unlock()
+
+	m.fill()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.step()
+}
+
+// fill gives each console input that has nothing on offer the next number
+// from its stream. Streams may block until they have a number, so the machine
+// isn't locked while they're read.
+func (m *Machine) fill() {
+	m.mu.Lock()
+	var empty []*consoleIn
+	if m.haltedBy == nil {
+		for _, cin := range m.inputs {
+			if cin.empty() {
+				empty = append(empty, cin)
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	for _, cin := range empty {
+		n, ok := cin.stream.Next()
+
+		m.mu.Lock()
+		cin.offer(n, ok)
+		m.mu.Unlock()
+	}
+}
+
+// step advances the whole machine by one cycle. Every node steps before any
+// node commits, so each node sees the machine as it was at the start of the
+// cycle no matter what order the nodes are visited in. It returns true if any
+// node made progress and no node ran HCF.
+func (m *Machine) step() bool {
+	if m.haltedBy != nil {
+		// The machine is on fire and can't run anymore
+		return false
+	}
+
+	// Console inputs were given their next number before the cycle started.
+	// Console outputs take numbers written to them in an earlier cycle.
+	progressed := false
+	for _, cin := range m.inputs {
+		cin.starved = false
+	}
+	for _, cout := range m.outputs {
+		cout.take()
+	}
+	if m.tracer != nil {
+		m.tracer.cycle = m.cycle + 1
+	}
+
+	for _, row := range m.nodes {
+		for _, elem := range row {
+			if elem.step() {
+				progressed = true
+			}
+			if en, ok := elem.(*executionNode); ok && en.onFire && m.haltedBy == nil {
+				m.haltedBy = en
+			}
+		}
+	}
+
+	for _, row := range m.nodes {
+		for _, elem := range row {
+			if elem.commit() {
+				progressed = true
+			}
+			elem.countMode()
+		}
+	}
+
+	m.cycle++
+
+	// Keep track of when output was last written for scoring
+	written := 0
+	for _, cout := range m.outputs {
+		written += cout.written
+	}
+	if written != m.outputCount {
+		m.outputCount = written
+		m.outputCycle = m.cycle
+	}
+
+	if m.vcd != nil {
+		m.vcd.sample(m.cycle)
+	}
+
+	m.stalled = !progressed
+	return progressed && m.haltedBy == nil
+}
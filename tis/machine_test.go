@@ -0,0 +1,471 @@
+package tis
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newExample creates a machine for the example project that reads the given
+// input. Its output is written to the returned buffer.
+func newExample(t *testing.T, input string) (*Machine, *bytes.Buffer) {
+	config, err := LoadConfig("../example/machine.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	mach, err := NewMachine(config,
+		map[string]InputStream{"IN.A": NewTextInput(strings.NewReader(input))},
+		map[string]OutputStream{"OUT.A": NewTextOutput(&out)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Load the example code into each execution node
+	for y, row := range mach.nodes {
+		for x, elem := range row {
+			if en, ok := elem.(*executionNode); ok {
+				data, err := ioutil.ReadFile("../example/" + strconv.Itoa(x) + "-" + strconv.Itoa(y) + ".tis")
+				if err != nil && !os.IsNotExist(err) {
+					t.Fatal(err)
+				}
+				if err = en.load(string(data), false); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+
+	return mach, &out
+}
+
+// newTestMachine creates a machine from the given config with the given
+// streams for its console inputs and outputs, and loads the code into it if
+// there is any. Any error fails the test.
+func newTestMachine(t *testing.T, configJSON string, code map[string]string, inputs map[string]InputStream, outputs map[string]OutputStream) *Machine {
+	config, err := ParseConfig([]byte(configJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mach, err := NewMachine(config, inputs, outputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != nil {
+		if err := mach.Load(code); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return mach
+}
+
+// runExample runs the example project with the given input until it has
+// written the given number of lines of output. It returns the output and the
+// cycle the last line was written on.
+func runExample(t *testing.T, input string, lines int) (string, int) {
+	mach, out := newExample(t, input)
+
+	for strings.Count(out.String(), "\n") < lines {
+		if mach.cycle > 1000 {
+			t.Fatal("machine took too many cycles to produce", lines, "lines of output")
+		}
+		mach.Step()
+	}
+
+	return out.String(), mach.cycle
+}
+
+// TestMachineOutput tests that numbers make it through the example project
+// intact.
+func TestMachineOutput(t *testing.T) {
+	out, _ := runExample(t, "1\n2\n3\n", 3)
+
+	if out != "4\n5\n6\n" {
+		t.Error("expected the output 4, 5, 6 but got", strings.Fields(out))
+	}
+}
+
+// TestMachineIsDeterministic tests that the same program and input always
+// produce the same output on the same cycle.
+func TestMachineIsDeterministic(t *testing.T) {
+	expectedOut, expectedCycle := runExample(t, "5\n-7\n20\n998\n", 4)
+
+	for i := 0; i < 20; i++ {
+		out, cycle := runExample(t, "5\n-7\n20\n998\n", 4)
+		if out != expectedOut {
+			t.Error("expected the output", strings.Fields(expectedOut), "but got", strings.Fields(out))
+		}
+		if cycle != expectedCycle {
+			t.Error("expected the output to finish on cycle", expectedCycle, "but it finished on cycle", cycle)
+		}
+	}
+}
+
+// TestMachineScore tests that the score of the example project counts the
+// cycles, nodes and instructions used.
+func TestMachineScore(t *testing.T) {
+	_, cycle := runExample(t, "1\n2\n3\n", 3)
+
+	mach, _ := newExample(t, "1\n2\n3\n")
+	mach.Run()
+
+	s := mach.Score()
+	if s.Cycles != cycle || cycle != 14 {
+		t.Error("expected the score to count 14 cycles, but counted", s.Cycles, "with the last output on cycle", cycle)
+	}
+	if s.Nodes != 2 {
+		t.Error("expected the score to count 2 nodes, but counted", s.Nodes)
+	}
+	if s.Instructions != 4 {
+		t.Error("expected the score to count 4 instructions, but counted", s.Instructions)
+	}
+}
+
+// TestMachineConsoleTiming tests that numbers move through console inputs and
+// outputs on the same schedule as through links between nodes. Moving a number
+// from the input to the output takes a cycle to read it and write it, and
+// another for the output to take it.
+func TestMachineConsoleTiming(t *testing.T) {
+	out := &SliceOutput{}
+	mach := newTestMachine(t, `{
+		"nodes": [["e"]],
+		"consoleIn": {"side": "top", "pos": 0},
+		"consoleOut": {"side": "bottom", "pos": 0}}`,
+		map[string]string{"0-0": "mov up down\n"},
+		map[string]InputStream{"IN": NewSliceInput([]Number{1, 2, 3})},
+		map[string]OutputStream{"OUT": out})
+
+	for i := 1; i <= 6; i++ {
+		mach.Step()
+		if len(out.Values) != i/2 {
+			t.Errorf("expected %v numbers to be written after cycle %v, got %v", i/2, i, out.Values)
+		}
+	}
+	if s := mach.Score(); s.Cycles != 6 {
+		t.Error("expected the score to count 6 cycles, but counted", s.Cycles)
+	}
+}
+
+// TestMachineDeadlock tests that a machine where every node waits on the
+// other is reported as deadlocked, along with what each node is stuck on.
+func TestMachineDeadlock(t *testing.T) {
+	// Both nodes write to each other, so neither can ever read
+	var out bytes.Buffer
+	mach := newTestMachine(t, `{
+		"nodes": [["e", "e"]],
+		"consoleIn": {"side": "top", "pos": 0},
+		"consoleOut": {"side": "bottom", "pos": 1}}`,
+		map[string]string{
+			"0-0": "nop\nmov 1 RIGHT\n",
+			"1-0": "mov 2 LEFT\n"},
+		map[string]InputStream{"IN": NewSliceInput(nil)},
+		map[string]OutputStream{"OUT": NewTextOutput(&out)})
+	mach.Run()
+
+	err := mach.Deadlock()
+	if err == nil {
+		t.Fatal("expected the machine to be deadlocked")
+	}
+	for _, expected := range []string{
+		"0-0 is waiting to write RIGHT on line 2: MOV 1 RIGHT",
+		"1-0 is waiting to write LEFT on line 1: MOV 2 LEFT"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Error("expected the deadlock report to contain '"+expected+"' but got", err)
+		}
+	}
+}
+
+// TestMachineFinishesWithoutDeadlock tests that running out of console input
+// isn't considered a deadlock.
+func TestMachineFinishesWithoutDeadlock(t *testing.T) {
+	mach, _ := newExample(t, "1\n2\n")
+	mach.Run()
+
+	if err := mach.Deadlock(); err != nil {
+		t.Error("expected the machine to finish normally, but got", err)
+	}
+}
+
+// TestMachineNamedStreams tests that each named console input and output is
+// wired to its own stream.
+func TestMachineNamedStreams(t *testing.T) {
+	// Node 0-0 negates IN.A into OUT.A, and node 1-0 passes IN.B to OUT.B
+	outA, outB := &SliceOutput{}, &SliceOutput{}
+	mach := newTestMachine(t, `{
+		"nodes": [["e", "e"]],
+		"inputs": [
+			{"name": "IN.A", "side": "top", "pos": 0},
+			{"name": "IN.B", "side": "top", "pos": 1}],
+		"outputs": [
+			{"name": "OUT.A", "side": "left", "pos": 0},
+			{"name": "OUT.B", "side": "bottom", "pos": 1}]}`,
+		map[string]string{
+			"0-0": "mov UP ACC\nneg\nmov ACC LEFT\n",
+			"1-0": "mov UP DOWN\n"},
+		map[string]InputStream{
+			"IN.A": NewSliceInput([]Number{1, 2}),
+			"IN.B": NewSliceInput([]Number{10, 20})},
+		map[string]OutputStream{
+			"OUT.A": outA,
+			"OUT.B": outB})
+	mach.Run()
+
+	if len(outA.Values) != 2 || outA.Values[0] != -1 || outA.Values[1] != -2 {
+		t.Error("expected OUT.A to be [-1 -2] but got", outA.Values)
+	}
+	if len(outB.Values) != 2 || outB.Values[0] != 10 || outB.Values[1] != 20 {
+		t.Error("expected OUT.B to be [10 20] but got", outB.Values)
+	}
+}
+
+// TestMachineConfigRejectsBadStreams tests that console inputs and outputs
+// that overlap, share a name or have a bad type are rejected.
+func TestMachineConfigRejectsBadStreams(t *testing.T) {
+	configs := []string{
+		`{"nodes": [["e", "e"]],
+			"inputs": [{"name": "IN.A", "side": "top", "pos": 0}],
+			"outputs": [{"name": "OUT.A", "side": "top", "pos": 0}]}`,
+		`{"nodes": [["e", "e"]],
+			"inputs": [{"name": "IN.A", "side": "top", "pos": 0}, {"name": "IN.A", "side": "top", "pos": 1}]}`,
+		`{"nodes": [["e", "e"]],
+			"consoleIn": {"side": "left", "pos": 0},
+			"inputs": [{"name": "IN.B", "side": "left", "pos": 0}]}`,
+		`{"nodes": [["e", "e"]],
+			"outputs": [{"name": "OUT.A", "side": "right", "pos": 1}]}`,
+		`{"nodes": [["e", "e"]],
+			"inputs": [{"name": "IN.A", "side": "top", "pos": 0, "type": "image"}]}`,
+		`{"nodes": [["e", "e"]],
+			"outputs": [{"name": "OUT.A", "side": "top", "pos": 0, "type": "speaker"}]}`}
+
+	for _, config := range configs {
+		if _, err := ParseConfig([]byte(config)); err == nil {
+			t.Error("expected the config to be rejected:", config)
+		}
+	}
+}
+
+// TestMachineConfigImageDefaults tests that image outputs default to the size
+// of the game's display.
+func TestMachineConfigImageDefaults(t *testing.T) {
+	config, err := ParseConfig([]byte(`{"nodes": [["e"]],
+		"outputs": [{"name": "IMAGE", "side": "bottom", "pos": 0, "type": "image"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sc := config.Outputs[0]
+	if sc.Width != 30 || sc.Height != 18 {
+		t.Errorf("expected a 30x18 display, got %vx%v", sc.Width, sc.Height)
+	}
+}
+
+// TestMachineStrict tests that a strict config holds every node's code to the
+// game's limits, and that the error says which node broke them.
+func TestMachineStrict(t *testing.T) {
+	code := map[string]string{"1-0": strings.Repeat("NOP\n", 16)}
+
+	for _, strict := range []bool{false, true} {
+		mach := newTestMachine(t, `{"nodes": [["e", "e"]], "strict": `+strconv.FormatBool(strict)+`}`, nil, nil, nil)
+
+		err := mach.Load(code)
+		if !strict && err != nil {
+			t.Error("expected the code to load without strict limits, got", err)
+		}
+		if strict && (err == nil || !strings.Contains(err.Error(), "node 1-0") || !strings.Contains(err.Error(), "limit of 15")) {
+			t.Error("expected the code to be rejected for node 1-0 being over 15 lines, got", err)
+		}
+	}
+}
+
+// TestMachineHCF tests that HCF stops the whole machine at the end of the
+// cycle it runs on, after any output from that cycle is written.
+func TestMachineHCF(t *testing.T) {
+	// Node 1-0 would run forever if the machine didn't stop
+	out := &SliceOutput{}
+	mach := newTestMachine(t, `{
+		"nodes": [["e", "e"]],
+		"outputs": [{"name": "OUT", "side": "bottom", "pos": 0}]}`,
+		map[string]string{
+			"0-0": "mov 1 down\nmov 2 down\nhcf\nmov 3 down\n",
+			"1-0": "l: add 1\njmp l\n"},
+		nil, map[string]OutputStream{"OUT": out})
+	mach.Run()
+
+	if len(out.Values) != 2 {
+		t.Error("expected 2 numbers to be written before HCF, but got", out.Values)
+	}
+	if mach.Step() {
+		t.Error("expected the machine to stay stopped after HCF")
+	}
+
+	err := mach.Halted()
+	if err == nil || !strings.Contains(err.Error(), "node 0-0") || !strings.Contains(err.Error(), "line 3") {
+		t.Error("expected the machine to be halted by node 0-0 on line 3, but got", err)
+	}
+	if err := mach.Deadlock(); err != nil {
+		t.Error("expected HCF not to count as a deadlock, but got", err)
+	}
+}
+
+// TestMachineReload tests that loading code again replaces the code that was
+// there and starts the nodes over, taking back numbers they were writing.
+func TestMachineReload(t *testing.T) {
+	mach := newTestMachine(t, `{"nodes": [["e", "e"]]}`, nil, nil, nil)
+
+	code := map[string]string{"0-0": "l: add 1\nsav\nmov acc right\njmp l\n"}
+	for i := 0; i < 2; i++ {
+		if err := mach.Load(code); err != nil {
+			t.Fatal("expected loading the code again to work, got", err)
+		}
+		n, _ := mach.Node("0-0")
+		if n.Instructions != 4 || n.Line != 1 || n.ACC != 0 || n.BAK != 0 || n.Executed != 0 || n.Modes.Total() != 0 {
+			t.Errorf("expected node 0-0 to start over with 4 instructions, got %+v", n)
+		}
+		if len(n.Sending) != 0 {
+			t.Errorf("expected node 0-0 not to be sending anything, got %v", n.Sending)
+		}
+
+		// Leave the node waiting to write to the empty node
+		for j := 0; j < 4; j++ {
+			mach.Step()
+		}
+	}
+}
+
+// TestMachineRunTwice tests that running a machine again after it stopped, or
+// while it runs in the background, is safe.
+func TestMachineRunTwice(t *testing.T) {
+	mach := newTestMachine(t, `{"nodes": [["e"]]}`, map[string]string{"0-0": "mov up acc\n"}, nil, nil)
+
+	mach.Start()
+	mach.Run()
+	<-mach.Done()
+	mach.Run()
+}
+
+// blockingInput is an input stream that waits for each number to be sent to
+// it. It says when it starts waiting.
+type blockingInput struct {
+	waiting chan struct{}
+	values  chan Number
+}
+
+func (bi blockingInput) Next() (Number, bool) {
+	bi.waiting <- struct{}{}
+	n, ok := <-bi.values
+	return n, ok
+}
+
+// TestMachineQueryWhileInputBlocks tests that a running machine can still be
+// queried while it waits for a console input's stream to give it a number.
+func TestMachineQueryWhileInputBlocks(t *testing.T) {
+	in := blockingInput{
+		waiting: make(chan struct{}),
+		values:  make(chan Number)}
+	mach := newTestMachine(t, `{
+		"nodes": [["e"]],
+		"consoleIn": {"side": "top", "pos": 0}}`,
+		map[string]string{"0-0": "mov up acc\n"},
+		map[string]InputStream{"IN": in}, nil)
+
+	// Give the machine one number, and wait for it to ask for the next
+	mach.Start()
+	<-in.waiting
+	in.values <- 5
+	<-in.waiting
+
+	queried := make(chan NodeState)
+	go func() {
+		mach.Score()
+		mach.Deadlock()
+		n, _ := mach.Node("0-0")
+		queried <- n
+	}()
+	select {
+	case n := <-queried:
+		if n.ACC != 5 {
+			t.Error("expected ACC to be 5, got", n.ACC)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the machine to be queried while its input blocks")
+	}
+
+	close(in.values)
+	<-mach.Done()
+}
+
+// TestMachineLoadErrors tests that loading reports the problems in every
+// node's code instead of stopping at the first node with a problem.
+func TestMachineLoadErrors(t *testing.T) {
+	mach := newTestMachine(t, `{"nodes": [["e", "e", "e"]]}`, nil, nil, nil)
+
+	err := mach.Load(map[string]string{
+		"0-0": "nop\nfoo\n",
+		"1-0": "mov 1 acc\n",
+		"2-0": "jmp nowhere\n"})
+	errs, ok := err.(ParseErrors)
+	if !ok {
+		t.Fatal("expected ParseErrors, got", err)
+	}
+	if len(errs) != 2 || errs[0].Node != "0-0" || errs[1].Node != "2-0" {
+		t.Error("expected an error for nodes 0-0 and 2-0, got", errs)
+	}
+}
+
+// TestMachineJRO tests that JRO jumps by its offset the way it does in the
+// game, stopping at the first or last instruction instead of wrapping around.
+func TestMachineJRO(t *testing.T) {
+	testCases := []struct {
+		code  string
+		steps int
+		line  int
+		acc   Number
+	}{
+		// JRO 0 runs itself forever
+		{code: "jro 0\nadd 1\n", steps: 5, line: 1, acc: 0},
+		// Offsets are relative to the JRO
+		{code: "jro 2\nadd 1\nadd 2\n", steps: 2, line: 1, acc: 2},
+		{code: "add 1\nmov -1 acc\njro acc\n", steps: 4, line: 3, acc: -1},
+		// Jumping past either end stops at the first or last instruction
+		{code: "add 1\njro -5\nadd 10\n", steps: 3, line: 2, acc: 2},
+		{code: "jro 999\nadd 1\nadd 2\n", steps: 2, line: 1, acc: 2},
+		{code: "jro -999\n", steps: 3, line: 1, acc: 0}}
+
+	for _, testCase := range testCases {
+		mach := newTestMachine(t, `{"nodes": [["e"]]}`, map[string]string{"0-0": testCase.code}, nil, nil)
+
+		for i := 0; i < testCase.steps; i++ {
+			mach.Step()
+		}
+
+		state, _ := mach.Node("0-0")
+		if state.Line != testCase.line || state.ACC != testCase.acc {
+			t.Errorf("expected %q to be on line %v with ACC %v after %v cycles, but it's on line %v with ACC %v",
+				testCase.code, testCase.line, testCase.acc, testCase.steps, state.Line, state.ACC)
+		}
+	}
+}
+
+// TestMachineJROPort tests that JRO can take its offset from a port, waiting
+// until a number is there to read.
+func TestMachineJROPort(t *testing.T) {
+	mach := newTestMachine(t, `{
+		"nodes": [["e"]],
+		"inputs": [{"name": "IN", "side": "top", "pos": 0}]}`,
+		map[string]string{"0-0": "jro up\nadd 1\nadd 2\nadd 4\n"},
+		map[string]InputStream{"IN": NewSliceInput([]Number{2, -5})}, nil)
+	mach.Run()
+
+	// The first jump skips to ADD 2, and the second stops at the JRO itself,
+	// which then waits for input that never comes
+	state, _ := mach.Node("0-0")
+	if state.ACC != 6 || state.Line != 1 || state.Waiting == "" {
+		t.Errorf("expected the node to wait on line 1 with ACC 6, but got %+v", state)
+	}
+}
@@ -0,0 +1,53 @@
+package tis
+
+// Mode is what a node spent its last cycle doing, as the game shows it.
+type Mode int
+
+const (
+	ModeIdle  Mode = iota // The node has no code, or a stack node is empty
+	ModeRun               // The node ran an instruction, or a stack node moved a number
+	ModeRead              // The node is waiting to read a number
+	ModeWrite             // The node is waiting for a number it wrote to be taken
+	modeCount
+)
+
+// String returns the mode's name as the game shows it.
+func (m Mode) String() string {
+	switch m {
+	case ModeIdle:
+		return "IDLE"
+	case ModeRun:
+		return "RUN"
+	case ModeRead:
+		return "READ"
+	case ModeWrite:
+		return "WRTE"
+	}
+
+	return "UNKNOWN"
+}
+
+// ModeCycles counts how many cycles a node spent in each mode, indexed by
+// mode.
+type ModeCycles [modeCount]int
+
+// Total returns how many cycles were counted.
+func (mc ModeCycles) Total() int {
+	total := 0
+	for _, cycles := range mc {
+		total += cycles
+	}
+
+	return total
+}
+
+// Percent returns the share of counted cycles spent in the given mode, from 0
+// to 100.
+func (mc ModeCycles) Percent(m Mode) float64 {
+	total := mc.Total()
+	if total == 0 {
+		return 0
+	}
+
+	return float64(mc[m]) * 100 / float64(total)
+}
@@ -0,0 +1,19 @@
+package tis
+
+// node represents a node with four ends that can read and write from those
+// ends. Nodes run in lockstep with each other. Every cycle, each node in the
+// machine is stepped, and then each node commits the results of that step.
+// Both return true if the node made any progress. Once a cycle is over, the
+// node's mode is counted.
+type node interface {
+	getRight() port
+	getLeft() port
+	getUp() port
+	getDown() port
+
+	step() bool
+	commit() bool
+
+	mode() Mode
+	countMode()
+}
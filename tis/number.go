@@ -0,0 +1,59 @@
+package tis
+
+const (
+	numberMaxValue = 999
+	numberMinValue = -999
+)
+
+// Number is an immutible integer value that follows the TIS-100's weird number
+// capping rules. It should be treated as a read-only value and not be modified
+// directly.
+type Number int
+
+// numberReader describes an object that can act as a source of a number. If no
+// number is available yet, false is returned and the read should be tried again
+// on the next cycle.
+type numberReader interface {
+	readNum() (Number, bool)
+}
+
+// numberWriter describes an object that can take in a number. The number is
+// wrapped in a transfer, which is marked as taken once whatever is on the other
+// end has accepted it.
+type numberWriter interface {
+	writeNum(*transfer)
+}
+
+// numberReadWriter describes an object that can act as a source of a number
+// and take in a number.
+type numberReadWriter interface {
+	numberReader
+	numberWriter
+}
+
+func capNumber(val Number) Number {
+	if val > numberMaxValue {
+		return Number(numberMaxValue)
+	} else if val < numberMinValue {
+		return Number(numberMinValue)
+	}
+
+	return val
+}
+
+// NewNumber returns a new valid TIS-100 number based on the given integer.
+func NewNumber(val int) Number {
+	return capNumber(Number(val))
+}
+
+// addNum adds the given integer to the number and returns a valid TIS-100
+// number.
+func addNum(n Number, val int) Number {
+	return NewNumber(int(n) + val)
+}
+
+// subtractNum subtracts the given integer from the number and returns a
+// valid TIS-100 number.
+func subtractNum(n Number, val int) Number {
+	return NewNumber(int(n) - val)
+}
@@ -0,0 +1,76 @@
+package tis
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseError is a problem with the code given to an execution node.
+type ParseError struct {
+	Node    string // The name of the node the code is for, like "1-0"
+	Line    int    // The line the problem is on, starting at 1
+	Column  int    // The column the problem is at, starting at 1
+	Source  string // The line of code the problem is on
+	Message string
+}
+
+func newParseError(message string, c char) *ParseError {
+	return &ParseError{
+		Line:    c.line + 1,
+		Column:  c.pos + 1,
+		Message: message}
+}
+
+func (e *ParseError) Error() string {
+	msg := fmt.Sprint(e.Message, " at line ", e.Line, ", column ", e.Column)
+	if e.Node != "" {
+		msg = "error in node " + e.Node + ": " + msg
+	}
+
+	return msg
+}
+
+// Snippet returns the line of code the error is on, followed by a line with a
+// caret under the column the error is at.
+func (e *ParseError) Snippet() string {
+	// Copy any tabs before the column so the caret lines up the same way the
+	// code does
+	var indent []rune
+	for i, c := range []rune(e.Source) {
+		if i >= e.Column-1 {
+			break
+		}
+		if c == '\t' {
+			indent = append(indent, '\t')
+		} else {
+			indent = append(indent, ' ')
+		}
+	}
+	for len(indent) < e.Column-1 {
+		indent = append(indent, ' ')
+	}
+
+	return e.Source + "\n" + string(indent) + "^"
+}
+
+// ParseErrors is every problem found in the code given to a machine, in the
+// order the problems were found.
+type ParseErrors []*ParseError
+
+func (errs ParseErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// orNil returns the errors as an error, or nil if there aren't any.
+func (errs ParseErrors) orNil() error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
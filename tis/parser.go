@@ -0,0 +1,295 @@
+package tis
+
+import (
+	"errors"
+	"strconv"
+)
+
+// parserState represents what the parser expects next.
+type parserState int
+
+const (
+	parserStateNone parserState = iota
+	parserStateInstructionSpecific
+)
+
+// labelRef is a use of a label by a jump instruction, which is resolved once
+// every label in the node is known.
+type labelRef struct {
+	ins  jumpInstruction
+	char char // Where the label was used
+}
+
+// parser is a TIS-100 instruction parser. It constructs full, valid
+// instructions from lexer tokens.
+type parser struct {
+	lex   lexer
+	state parserState
+}
+
+// newParser creates a new parser that reads tokens from the given lexer.
+func newParser(lex lexer) parser {
+	return parser{
+		lex: lex}
+}
+
+// parse parses the tokens into instructions, or returns an error if the tokens
+// don't create a valid instruction for whatever reason. The instructions are
+// put into the given execution node. Each instruction and its operands have to
+// be on the same line, and each operand is checked against the instruction's
+// spec. Once every instruction is parsed, each jump's label is resolved to the
+// index of the instruction it jumps to. After an error, parsing picks up again
+// on the next line so that every problem in the code is reported.
+func (p *parser) parse(exNode *executionNode) error {
+	var currPattern []operandKind
+	var builder instruction
+	var insName string
+	var insChar char // Where the instruction being built starts
+	var argPos int
+	var instructionCnt int
+	var breakpoint bool
+	var refs []labelRef
+	var errs ParseErrors
+	skipLine := -1
+	lastLine := -1 // The line the last finished instruction is on
+
+	// fail records an error and throws out the rest of the line it's on
+	fail := func(message string, c char) {
+		errs = append(errs, newParseError(message, c))
+		p.state = parserStateNone
+		skipLine = c.line
+	}
+
+	// finish adds the built instruction to the node
+	finish := func() {
+		exNode.instructions = append(exNode.instructions, builder)
+		p.state = parserStateNone
+		instructionCnt++
+		lastLine = insChar.line
+	}
+
+	// Loop through every lexical token
+	for t, hasNext := p.lex.next(); hasNext; t, hasNext = p.lex.next() {
+		if t.startingChar.line == skipLine {
+			continue
+		}
+
+		if p.state == parserStateInstructionSpecific && t.startingChar.line != insChar.line {
+			// The instruction's line ended before all of its operands were
+			// given. The token is dealt with as the start of something new.
+			errs = append(errs, newParseError(insName+" expects "+operandCount(len(currPattern))+" but was given "+strconv.Itoa(argPos), insChar))
+			p.state = parserStateNone
+		}
+
+		switch p.state {
+		case parserStateNone:
+			// The parser doesn't know what to expect
+
+			if t.startingChar.line == lastLine {
+				// Nothing can follow an instruction's operands on its line
+				fail("unexpected '"+t.data+"' after "+insName+", which takes "+operandCount(len(currPattern)), t.startingChar)
+				continue
+			}
+
+			switch t.tType {
+			case tokenName:
+				// The next token is a name, meaning that it's the start of an
+				// instruction
+
+				// Try to find the pattern for the given instruction so we know
+				// how to parse it
+				if val, err := patternFromName(t.data); err == nil {
+					// Get the buildable instruction from the name
+					if val2, err := instructionFromName(t.data); err == nil {
+						builder = val2
+						builder.base().line = t.startingChar.line
+						builder.base().text = t.data
+						builder.base().breakpoint = breakpoint
+						breakpoint = false
+						insName = t.data
+						insChar = t.startingChar
+						currPattern = val
+						argPos = 0
+					} else {
+						fail(err.Error(), t.startingChar)
+						continue
+					}
+
+					if len(val) > 0 {
+						// The instruction has arguments that need to be parsed.
+						// Start parsing tokens according to the specific
+						// instruction
+						p.state = parserStateInstructionSpecific
+					} else {
+						// The instruction has no arguments and can be
+						// immediately added
+						finish()
+					}
+				} else {
+					fail(err.Error(), t.startingChar)
+					continue
+				}
+			case tokenLabel:
+				// The next token is a label
+
+				// Check that the label does not already exist
+				if _, ok := exNode.labels[t.data]; ok {
+					fail("duplicate label '"+t.data+"'", t.startingChar)
+					continue
+				}
+
+				// Set the label to point to the "address" of the next instruction
+				exNode.labels[t.data] = instructionCnt
+			case tokenNumber:
+				// The next token is a number. No operations start with a
+				// number, so this is an error.
+
+				fail("unexpected number '"+t.data+"'", t.startingChar)
+				continue
+			case tokenBreakpoint:
+				// The next token is a breakpoint marker, so the next
+				// instruction gets a breakpoint. Breakpoints only matter when
+				// running under a debugger.
+
+				breakpoint = true
+			}
+		case parserStateInstructionSpecific:
+			// The parser is parsing an instruction
+
+			if t.tType == tokenBreakpoint {
+				fail("unexpected breakpoint marker in the middle of an instruction", t.startingChar)
+				continue
+			}
+
+			// Check that the token can be used as the next operand and feed it
+			// into the builder
+			arg, err := operand(exNode, insName, currPattern[argPos], t)
+			if err != nil {
+				fail(err.Error(), t.startingChar)
+				continue
+			}
+			builder.setArg(arg, argPos)
+			builder.base().text += " " + t.data
+			if j, ok := builder.(jumpInstruction); ok {
+				refs = append(refs, labelRef{
+					ins:  j,
+					char: t.startingChar})
+			}
+
+			// Go to the next argument
+			argPos++
+
+			// Check if the instruction is finished building
+			if argPos >= len(currPattern) {
+				finish()
+			}
+		}
+	}
+
+	if p.state == parserStateInstructionSpecific {
+		// The code ended before all of the last instruction's operands were
+		// given
+		errs = append(errs, newParseError(insName+" expects "+operandCount(len(currPattern))+" but was given "+strconv.Itoa(argPos), insChar))
+		p.state = parserStateNone
+	}
+
+	// Point every jump at the instruction its label is on. A label after the
+	// last instruction wraps around to the first.
+	for _, ref := range refs {
+		j := ref.ins.jump()
+		i, ok := exNode.labels[j.l]
+		if !ok {
+			errs = append(errs, newParseError("undefined label '"+j.l+"'", ref.char))
+			continue
+		}
+		if i >= len(exNode.instructions) {
+			i = 0
+		}
+		j.target = i
+	}
+
+	return errs.orNil()
+}
+
+// operand returns what the given token refers to when it's used as an operand
+// of the given kind by the named instruction. Numbers become a register
+// holding the number, and register and port names become the node's register
+// or port. An error is returned if the token can't be used as that kind of
+// operand. Like in the game, BAK can't be used as an operand at all, and is
+// only reachable through SAV and SWP.
+func operand(exNode *executionNode, insName string, kind operandKind, t token) (interface{}, error) {
+	if t.tType == tokenLabel {
+		return nil, errors.New("unexpected label '" + t.data + "' in the middle of an instruction")
+	}
+
+	var named numberReadWriter
+	if t.tType == tokenName {
+		switch t.data {
+		case "ACC":
+			named = exNode.acc
+		case "BAK":
+			named = exNode.bak
+		case "NIL":
+			named = &nilReg
+		case "LEFT":
+			named = exNode.left
+		case "RIGHT":
+			named = exNode.right
+		case "UP":
+			named = exNode.up
+		case "DOWN":
+			named = exNode.down
+		case "ANY":
+			named = exNode.any
+		case "LAST":
+			named = exNode.last
+		}
+	}
+
+	if named == exNode.bak && kind != operandLabel {
+		return nil, errors.New(insName + " can't use BAK, which is only reachable through SAV and SWP")
+	}
+
+	switch kind {
+	case operandSource:
+		if t.tType == tokenNumber {
+			// Check that the token's data is a valid number
+			val, err := strconv.Atoi(t.data)
+			if err != nil {
+				return nil, errors.New("'" + t.data + "' can't be parsed as a number")
+			}
+			if val != int(NewNumber(val)) {
+				return nil, errors.New("'" + t.data + "' falls outside the range of an acceptable TIS-100 number")
+			}
+
+			// Create a temporary register to serve the number
+			return newRegister(val), nil
+		}
+		if named == nil {
+			return nil, errors.New(insName + " can't read from '" + t.data + "', which isn't a number, register or port")
+		}
+		return named, nil
+	case operandDest:
+		if named == nil {
+			return nil, errors.New(insName + " can't write to '" + t.data + "', which isn't a register or port")
+		}
+		return named, nil
+	default:
+		if t.tType != tokenName || named != nil {
+			return nil, errors.New(insName + " expects a label, not '" + t.data + "'")
+		}
+		return t.data, nil
+	}
+}
+
+// operandCount describes how many operands an instruction takes.
+func operandCount(n int) string {
+	switch n {
+	case 0:
+		return "no operands"
+	case 1:
+		return "1 operand"
+	default:
+		return strconv.Itoa(n) + " operands"
+	}
+}
@@ -0,0 +1,264 @@
+package tis
+
+import "testing"
+
+func TestParser(t *testing.T) {
+	empty := newNodePort()
+	ex := newExecutionNode("0-0", empty, empty, empty, empty)
+
+	// Create a scanner with the test code
+	scan := newScanner()
+	scan.add("nop\nMyLabel: add 14\njmp MyLabel\n")
+
+	// Lex the test code
+	lex := newLexer(scan)
+	lex.lex()
+
+	// Parse the tokens
+	parse := newParser(lex)
+	if err := parse.parse(ex); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ex.instructions) < 3 {
+		t.Error("parser created fewer instructions than expected")
+	} else if len(ex.instructions) > 3 {
+		t.Error("parser created more instructions than expected")
+	}
+
+	if line, ok := ex.labels["MYLABEL"]; !ok {
+		t.Error("parser failed to find a label")
+	} else if line != 1 {
+		t.Error("parser found the label, but didn't point it at the correct line: expected 1, found", line)
+	}
+
+	if _, ok := ex.instructions[0].(*nop); !ok {
+		t.Error("parser failed to create an instruction of type nop")
+	}
+
+	if ins, ok := ex.instructions[1].(*add); !ok {
+		t.Error("parser failed to create an instruction of type add")
+	} else if ins.source == nil {
+		t.Error("parser failed to parse the first argument of the add instruction")
+	} else if n, _ := ins.source.readNum(); n != Number(14) {
+		t.Error("the value of the first argument in the add instruction is incorrect: expected 14, found", n)
+	}
+
+	if ins, ok := ex.instructions[2].(*jmp); !ok {
+		t.Error("parser failed to create an instruction of type jmp")
+	} else if ins.l != "MYLABEL" {
+		t.Error("parser read the jmp label incorrectly: expected 'MYLABEL', found '" + ins.l + "'")
+	} else if ins.target != 1 {
+		t.Error("parser resolved the jmp label incorrectly: expected 1, found", ins.target)
+	}
+}
+
+// TestParserLabels tests that jumps are resolved to the instruction after
+// their label, even when the label comes later in the code or after the last
+// instruction.
+func TestParserLabels(t *testing.T) {
+	empty := newNodePort()
+	ex := newExecutionNode("0-0", empty, empty, empty, empty)
+
+	if err := ex.load("jez end\nstart: nop\njmp start\nend:\n", false); err != nil {
+		t.Fatal(err)
+	}
+
+	if ins := ex.instructions[0].(*jez); ins.target != 0 {
+		t.Error("expected a label after the last instruction to wrap to 0, found", ins.target)
+	}
+	if ins := ex.instructions[2].(*jmp); ins.target != 1 {
+		t.Error("expected the jmp to be resolved to 1, found", ins.target)
+	}
+}
+
+// TestParserUndefinedLabel tests that jumping to a label that doesn't exist
+// is a parse error that says where the label was used.
+func TestParserUndefinedLabel(t *testing.T) {
+	empty := newNodePort()
+	ex := newExecutionNode("0-0", empty, empty, empty, empty)
+
+	err := ex.load("start: nop\n  jgz nowhere\n", false)
+	if err == nil {
+		t.Fatal("parser didn't fail even though a label is undefined")
+	}
+	if expected := "error in node 0-0: undefined label 'NOWHERE' at line 2, column 7"; err.Error() != expected {
+		t.Errorf("expected the error %q, got %q", expected, err)
+	}
+}
+
+// TestParserCollectsErrors tests that every problem in a node's code is
+// reported, each pointing at its line and column, and that the node is left
+// empty afterwards.
+func TestParserCollectsErrors(t *testing.T) {
+	empty := newNodePort()
+	ex := newExecutionNode("1-0", empty, empty, empty, empty)
+
+	err := ex.load("nop\n\tmov 1 acc&\n3 nop\njmp nowhere\nadd 1\n", false)
+	errs, ok := err.(ParseErrors)
+	if !ok {
+		t.Fatal("expected ParseErrors, got", err)
+	}
+
+	expected := []struct {
+		line, column int
+		snippet      string
+	}{
+		{2, 11, "\tmov 1 acc&\n\t         ^"},
+		{3, 1, "3 nop\n^"},
+		{4, 5, "jmp nowhere\n    ^"}}
+	if len(errs) != len(expected) {
+		t.Fatal("expected", len(expected), "errors, got", errs)
+	}
+	for i, e := range expected {
+		if errs[i].Node != "1-0" || errs[i].Line != e.line || errs[i].Column != e.column {
+			t.Errorf("expected error %v to be in node 1-0 at %v:%v, got %v", i, e.line, e.column, errs[i])
+		}
+		if snippet := errs[i].Snippet(); snippet != e.snippet {
+			t.Errorf("expected error %v to have the snippet %q, got %q", i, e.snippet, snippet)
+		}
+	}
+
+	if len(ex.instructions) != 0 {
+		t.Error("expected the node to be left empty, but it has", len(ex.instructions), "instructions")
+	}
+}
+
+// TestParserBreakpoints tests that breakpoint markers are recorded on the
+// instruction that follows them, and only on that instruction.
+func TestParserBreakpoints(t *testing.T) {
+	empty := newNodePort()
+	ex := newExecutionNode("0-0", empty, empty, empty, empty)
+
+	if err := ex.load("!nop\nnop\n!\nMyLabel: nop\n", false); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []bool{true, false, true}
+	if len(ex.instructions) != len(expected) {
+		t.Fatal("expected", len(expected), "instructions but got", len(ex.instructions))
+	}
+	for i, ins := range ex.instructions {
+		if ins.base().breakpoint != expected[i] {
+			t.Error("expected instruction", i, "to have a breakpoint set to", expected[i])
+		}
+	}
+
+	// A breakpoint marker can't be in the middle of an instruction
+	ex = newExecutionNode("0-0", empty, empty, empty, empty)
+	if err := ex.load("mov ! 1 ACC\n", false); err == nil {
+		t.Error("parser didn't fail even though a breakpoint marker was inside an instruction")
+	}
+}
+
+// TestParserGameSnippets tests that code written in the game parses the same
+// way it does there.
+func TestParserGameSnippets(t *testing.T) {
+	testCases := []struct {
+		code         string
+		instructions []string
+		labels       map[string]int
+	}{
+		{
+			// Signal Amplifier
+			code:         "## SIGNAL AMP\nSTART:\nMOV UP, ACC\nADD ACC\nMOV ACC, DOWN\nJMP START\n",
+			instructions: []string{"MOV UP ACC", "ADD ACC", "MOV ACC DOWN", "JMP START"},
+			labels:       map[string]int{"START": 0}},
+		{
+			// Signal Comparator
+			code:         "S:MOV UP,ACC\nJGZ G\nMOV 0,DOWN\nJMP S\nG:MOV 1,DOWN\n",
+			instructions: []string{"MOV UP ACC", "JGZ G", "MOV 0 DOWN", "JMP S", "MOV 1 DOWN"},
+			labels:       map[string]int{"S": 0, "G": 4}},
+		{
+			// Sequence Counter
+			code:         "MOV UP ACC # READ\nLOOP_1: JEZ OUT_2\n SUB 1\n JMP LOOP_1\nOUT_2:\n!MOV ACC, RIGHT",
+			instructions: []string{"MOV UP ACC", "JEZ OUT_2", "SUB 1", "JMP LOOP_1", "MOV ACC RIGHT"},
+			labels:       map[string]int{"LOOP_1": 1, "OUT_2": 4}}}
+
+	for _, testCase := range testCases {
+		empty := newNodePort()
+		ex := newExecutionNode("0-0", empty, empty, empty, empty)
+		if err := ex.load(testCase.code, true); err != nil {
+			t.Errorf("failed to parse %q: %v", testCase.code, err)
+			continue
+		}
+
+		if len(ex.instructions) != len(testCase.instructions) {
+			t.Errorf("expected %v instructions from %q but got %v", len(testCase.instructions), testCase.code, len(ex.instructions))
+			continue
+		}
+		for i, ins := range ex.instructions {
+			if ins.base().text != testCase.instructions[i] {
+				t.Errorf("expected instruction %v to be %q but got %q", i, testCase.instructions[i], ins.base().text)
+			}
+		}
+		for label, i := range testCase.labels {
+			if ex.labels[label] != i {
+				t.Errorf("expected label %v to point to %v but got %v", label, i, ex.labels[label])
+			}
+		}
+	}
+}
+
+// TestParserInvalidOperands tests that operands the instruction can't use are
+// parse errors that point at the operand, and that an instruction's operands
+// have to be on its line.
+func TestParserInvalidOperands(t *testing.T) {
+	testCases := []struct {
+		code     string
+		expected string
+	}{
+		{
+			code:     "mov 5 10",
+			expected: "MOV can't write to '10', which isn't a register or port at line 1, column 7"},
+		{
+			code:     "loop: add loop",
+			expected: "ADD can't read from 'LOOP', which isn't a number, register or port at line 1, column 11"},
+		{
+			code:     "mov acc 3",
+			expected: "MOV can't write to '3', which isn't a register or port at line 1, column 9"},
+		{
+			code:     "mov acc bak",
+			expected: "MOV can't use BAK, which is only reachable through SAV and SWP at line 1, column 9"},
+		{
+			code:     "add bak",
+			expected: "ADD can't use BAK, which is only reachable through SAV and SWP at line 1, column 5"},
+		{
+			code:     "jmp bak",
+			expected: "JMP expects a label, not 'BAK' at line 1, column 5"},
+		{
+			code:     "jmp 5",
+			expected: "JMP expects a label, not '5' at line 1, column 5"},
+		{
+			code:     "jez acc",
+			expected: "JEZ expects a label, not 'ACC' at line 1, column 5"},
+		{
+			code:     "mov 1000 acc",
+			expected: "'1000' falls outside the range of an acceptable TIS-100 number at line 1, column 5"},
+		{
+			code:     "nop 1",
+			expected: "unexpected '1' after NOP, which takes no operands at line 1, column 5"},
+		{
+			code:     "add 1 2",
+			expected: "unexpected '2' after ADD, which takes 1 operand at line 1, column 7"},
+		{
+			code:     "mov 1\nacc",
+			expected: "MOV expects 2 operands but was given 1 at line 1, column 1"},
+		{
+			code:     "nop\n  sub",
+			expected: "SUB expects 1 operand but was given 0 at line 2, column 3"}}
+
+	for _, testCase := range testCases {
+		empty := newNodePort()
+		ex := newExecutionNode("", empty, empty, empty, empty)
+
+		errs, ok := ex.load(testCase.code, false).(ParseErrors)
+		if !ok || len(errs) == 0 {
+			t.Errorf("expected %q to fail to parse", testCase.code)
+			continue
+		}
+		if errs[0].Error() != testCase.expected {
+			t.Errorf("expected %q to fail with %q, got %q", testCase.code, testCase.expected, errs[0])
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package tis
+
+// transfer is a single number on its way from one place to another. A
+// transfer may be offered on several ports at once, as happens with ANY, but
+// it can only be taken once.
+type transfer struct {
+	n         Number
+	published bool // Whether readers on the other side can see the number yet
+	taken     bool // Whether the number has been accepted
+	from      port // The port the number left through, once taken
+}
+
+// newTransfer creates a new unpublished transfer of the given number.
+func newTransfer(n Number) *transfer {
+	return &transfer{
+		n: n}
+}
+
+// available returns true if the transfer can be taken by a reader.
+func (t *transfer) available() bool {
+	return t != nil && t.published && !t.taken
+}
+
+// port is a numberReadWriter that connects a node to something outside of it.
+// Ports follow the TIS-100's two-phase cycle. Reads only see numbers that were
+// published in an earlier cycle, and numbers written during a cycle are only
+// published once the cycle is committed. This makes the result of a cycle
+// independent of the order nodes are stepped in.
+type port interface {
+	numberReadWriter
+}
+
+// nodePort is one end of a two-way link shared between two nodes. Each end
+// holds the transfer its own node is offering and reads from the transfer
+// offered by the other end.
+type nodePort struct {
+	offered *transfer
+	peer    *nodePort
+}
+
+// newNodePort creates a new link and returns one of its ends. The other end
+// is available as its peer. A link with nothing on the other end goes nowhere,
+// so reads and writes on it block forever.
+func newNodePort() *nodePort {
+	a, b := &nodePort{}, &nodePort{}
+	a.peer, b.peer = b, a
+
+	return a
+}
+
+// readNum takes the number offered by the other side of the port, if one has
+// been published.
+func (np *nodePort) readNum() (Number, bool) {
+	t := np.peer.offered
+	if !t.available() {
+		return 0, false
+	}
+
+	t.taken = true
+	t.from = np.peer
+	return t.n, true
+}
+
+// writeNum offers the transfer to the other side of the port. It is up to the
+// writer to publish the transfer when the cycle is committed.
+func (np *nodePort) writeNum(t *transfer) {
+	np.offered = t
+}
+
+// anyPort is a pseudo-port that reads and writes to the first available port
+// of the four given. Like other ports, it blocks until the operation can be
+// completed. It also keeps track of what the last used port was for the LAST
+// pseudo-port.
+type anyPort struct {
+	up, down, left, right port
+	lastUsedPort          port
+	pending               *transfer
+}
+
+// newAnyPort creates a new ANY port that queries from the given ports.
+func newAnyPort(up, down, left, right port) *anyPort {
+	ap := &anyPort{
+		up:    up,
+		down:  down,
+		left:  left,
+		right: right}
+
+	return ap
+}
+
+// ports returns the four ports in the order the game checks them.
+func (ap *anyPort) ports() []port {
+	return []port{ap.left, ap.right, ap.up, ap.down}
+}
+
+// readNum reads the first available number from the ports.
+func (ap *anyPort) readNum() (Number, bool) {
+	ap.pending = nil
+
+	for _, p := range ap.ports() {
+		if n, ok := p.readNum(); ok {
+			ap.lastUsedPort = p
+			return n, true
+		}
+	}
+
+	return 0, false
+}
+
+// writeNum offers the transfer on all four ports. Whichever port it is taken
+// through becomes the last used port.
+func (ap *anyPort) writeNum(t *transfer) {
+	ap.pending = t
+
+	for _, p := range ap.ports() {
+		p.writeNum(t)
+		if t.taken {
+			break
+		}
+	}
+}
+
+// last returns the port used by the most recent completed ANY operation, or
+// nil if there hasn't been one.
+func (ap *anyPort) last() port {
+	if ap.pending != nil && ap.pending.taken {
+		ap.lastUsedPort = ap.pending.from
+		ap.pending = nil
+	}
+
+	return ap.lastUsedPort
+}
+
+// lastPort is a pseudo-port that refers to whichever port the ANY pseudo-port
+// last used. Until ANY has been used, it behaves like NIL.
+type lastPort struct {
+	any *anyPort
+}
+
+// newLastPort creates a new LAST port that follows the given ANY port.
+func newLastPort(any *anyPort) *lastPort {
+	return &lastPort{
+		any: any}
+}
+
+// readNum reads from the last used port.
+func (lp *lastPort) readNum() (Number, bool) {
+	if p := lp.any.last(); p != nil {
+		return p.readNum()
+	}
+
+	return nilReg.readNum()
+}
+
+// writeNum writes to the last used port.
+func (lp *lastPort) writeNum(t *transfer) {
+	if p := lp.any.last(); p != nil {
+		p.writeNum(t)
+		return
+	}
+
+	nilReg.writeNum(t)
+}
@@ -0,0 +1,41 @@
+package tis
+
+// register is a basic number storage mechanism. It does not communicate
+// between nodes.
+type register struct {
+	value Number
+}
+
+// newRegister creates a new register holding the given value.
+func newRegister(value int) *register {
+	return &register{
+		value: NewNumber(value)}
+}
+
+// readNum returns the value the register is holding. A register always has a
+// value available.
+func (r *register) readNum() (Number, bool) {
+	return r.value, true
+}
+
+// writeNum sets the value of the register to the given value. The transfer is
+// taken immediately.
+func (r *register) writeNum(t *transfer) {
+	r.value = t.n
+	t.taken = true
+}
+
+// nilRegister is a pseudo-register that always reads as a zero and discards any
+// numbers that are written to it. There exists only one instance of this
+// pseudo-register.
+type nilRegister struct{}
+
+var nilReg nilRegister
+
+func (*nilRegister) readNum() (Number, bool) {
+	return Number(0), true
+}
+
+func (*nilRegister) writeNum(t *transfer) {
+	t.taken = true
+}
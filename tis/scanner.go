@@ -1,7 +1,13 @@
-package main
+package tis
 
 import "fmt"
 
+// The limits the game puts on the code in each node.
+const (
+	gameMaxLines      = 15
+	gameMaxLineLength = 18
+)
+
 // char represents a single character and marks where the character is in the
 // code.
 type char struct {
@@ -21,6 +27,8 @@ type scanner struct {
 	ignoringChars bool
 	currPos       int
 	currLine      int
+	lineLengths   []int // The length of each finished line, comments included
+	prev          rune  // The character added last
 }
 
 // newScanner creates a new scanner.
@@ -47,12 +55,18 @@ func (s *scanner) add(chars string) {
 		s.currPos++
 
 		if val == '\n' {
-			// Go to the next line and reset cursor position on newline
+			// Go to the next line and reset cursor position on newline. A
+			// carriage return in a CRLF line ending isn't part of the line.
+			length := s.currPos - 1
+			if s.prev == '\r' {
+				length--
+			}
+			s.lineLengths = append(s.lineLengths, length)
 			s.currLine++
 			s.currPos = 0
 			s.ignoringChars = false // Exit comment ignoring mode if we're there
 		}
-
+		s.prev = val
 	}
 }
 
@@ -66,3 +80,26 @@ func (s *scanner) next() (char, bool) {
 		return char{}, false
 	}
 }
+
+// checkGameLimits returns an error for each way the code added so far breaks
+// the game's limits on the number of lines in a node and the length of each
+// line.
+func (s *scanner) checkGameLimits() error {
+	lengths := s.lineLengths
+	if s.currPos > 0 {
+		// The last line doesn't end in a newline
+		lengths = append(lengths, s.currPos)
+	}
+
+	var errs ParseErrors
+	if len(lengths) > gameMaxLines {
+		errs = append(errs, newParseError(fmt.Sprint("code has ", len(lengths), " lines, over the game's limit of ", gameMaxLines), char{line: gameMaxLines}))
+	}
+	for i, length := range lengths {
+		if length > gameMaxLineLength {
+			errs = append(errs, newParseError(fmt.Sprint("line is ", length, " characters long, over the game's limit of ", gameMaxLineLength), char{line: i, pos: gameMaxLineLength}))
+		}
+	}
+
+	return errs.orNil()
+}
@@ -1,6 +1,9 @@
-package main
+package tis
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 // TestScanning tests the scanner for correct character order, character count,
 // and proper positioning and line count.
@@ -74,3 +77,47 @@ func TestScannerIgnoresComments(t *testing.T) {
 		expectedPos++
 	}
 }
+
+// TestScannerGameLimits tests that code is checked against the number of
+// lines and the line length the game allows.
+func TestScannerGameLimits(t *testing.T) {
+	testCases := []struct {
+		code     string
+		expected string
+	}{
+		{
+			code: strings.Repeat("NOP\n", 15)},
+		{
+			code: strings.Repeat("NOP\n", 14) + "MOV UP DOWN # 18ch"},
+		{
+			code: strings.Repeat("MOV UP DOWN # 18ch\r\n", 15)},
+		{
+			code:     "NOP\r\nMOV UP DOWN # 19 ch\r\n",
+			expected: "line is 19 characters long, over the game's limit of 18 at line 2, column 19"},
+		{
+			code:     strings.Repeat("NOP\n", 16),
+			expected: "code has 16 lines, over the game's limit of 15 at line 16, column 1"},
+		{
+			code:     "NOP\nMOV ACC DOWN # too long\n",
+			expected: "line is 23 characters long, over the game's limit of 18 at line 2, column 19"}}
+
+	for _, testCase := range testCases {
+		scan := newScanner()
+		scan.add(testCase.code)
+
+		err := scan.checkGameLimits()
+		if testCase.expected == "" && err != nil {
+			t.Errorf("expected %q to fit in the game, got %v", testCase.code, err)
+		} else if testCase.expected != "" && (err == nil || err.Error() != testCase.expected) {
+			t.Errorf("expected %q to fail with %q, got %v", testCase.code, testCase.expected, err)
+		}
+	}
+
+	// Every broken limit is reported, not just the first
+	scan := newScanner()
+	scan.add(strings.Repeat("NOP # a long comment\n", 16))
+	errs, ok := scan.checkGameLimits().(ParseErrors)
+	if !ok || len(errs) != 17 {
+		t.Errorf("expected 17 errors for 16 long lines, got %v", errs)
+	}
+}
@@ -0,0 +1,48 @@
+package tis
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Score summarizes how well a solution performed, using the same measurements
+// as the game's histograms.
+type Score struct {
+	Cycles       int `json:"cycles"`       // Cycles taken until the last output was written
+	Nodes        int `json:"nodes"`        // Execution nodes that have any code in them
+	Instructions int `json:"instructions"` // Instructions across all execution nodes
+}
+
+// Score measures the machine's solution as it stands.
+func (m *Machine) Score() Score {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := Score{
+		Cycles: m.outputCycle}
+
+	for _, row := range m.nodes {
+		for _, elem := range row {
+			if en, ok := elem.(*executionNode); ok && len(en.instructions) > 0 {
+				s.Nodes++
+				s.Instructions += len(en.instructions)
+			}
+		}
+	}
+
+	return s
+}
+
+func (s Score) String() string {
+	return fmt.Sprintf("Cycles: %v, Nodes: %v, Instructions: %v", s.Cycles, s.Nodes, s.Instructions)
+}
+
+// JSON returns the score as a JSON object.
+func (s Score) JSON() string {
+	data, err := json.Marshal(s)
+	if err != nil {
+		panic(err) // A score can always be represented as JSON
+	}
+
+	return string(data)
+}
@@ -0,0 +1,125 @@
+package tis
+
+// defaultStackCapacity is how many numbers a stack node holds if the config
+// doesn't say otherwise, which is how many the game's stack memory node holds.
+const defaultStackCapacity = 15
+
+// stackNode is a stack memory node. Numbers written to it from any side are
+// pushed, and the number on top is offered to every side until one takes it.
+// Once the stack is full, writers wait until there's room.
+type stackNode struct {
+	up, down, left, right port
+	values                []Number
+	incoming              []Number
+	offered               *transfer
+	popped                *transfer // The offered number taken most recently
+	capacity              int
+	moved                 bool       // Whether a number was pushed or popped last cycle
+	modes                 ModeCycles // How many cycles the node spent in each mode
+
+	name string
+}
+
+func newStackNode(name string, capacity int, up, down, left, right port) *stackNode {
+	return &stackNode{
+		name:     name,
+		capacity: capacity,
+		up:       up,
+		down:     down,
+		left:     left,
+		right:    right}
+}
+
+func (sn *stackNode) String() string {
+	return sn.name
+}
+
+// ports returns the stack node's four ports in the order they are served.
+func (sn *stackNode) ports() []port {
+	return []port{sn.left, sn.right, sn.up, sn.down}
+}
+
+// step takes any numbers written to the stack node, as long as there's room
+// for them. They are pushed when the cycle is committed. A number popped this
+// cycle doesn't make room until the next one.
+func (sn *stackNode) step() bool {
+	for _, p := range sn.ports() {
+		if len(sn.values)+len(sn.incoming) >= sn.capacity {
+			break
+		}
+		if n, ok := p.readNum(); ok {
+			sn.incoming = append(sn.incoming, n)
+		}
+	}
+
+	return len(sn.incoming) > 0
+}
+
+// commit pops the top of the stack if a node took it this cycle, pushes the
+// numbers written this cycle, and offers the new top of the stack on every
+// port. Only one node can take the offered number.
+func (sn *stackNode) commit() bool {
+	progressed := len(sn.incoming) > 0
+
+	if sn.offered != nil && sn.offered.taken {
+		sn.values = sn.values[:len(sn.values)-1]
+		sn.popped = sn.offered
+		progressed = true
+	}
+	sn.offered = nil
+
+	sn.values = append(sn.values, sn.incoming...)
+	sn.incoming = sn.incoming[:0]
+	sn.moved = progressed
+
+	if len(sn.values) == 0 {
+		return progressed
+	}
+
+	sn.offered = newTransfer(sn.values[len(sn.values)-1])
+	sn.offered.published = true
+	for _, p := range sn.ports() {
+		p.writeNum(sn.offered)
+		if sn.offered.taken {
+			progressed = true
+			break
+		}
+	}
+
+	return progressed
+}
+
+// mode returns what the node spent its last cycle doing. A stack node runs
+// when a number is pushed or popped, and otherwise waits for the number on top
+// to be taken. It's idle while it's empty.
+func (sn *stackNode) mode() Mode {
+	switch {
+	case sn.moved:
+		return ModeRun
+	case len(sn.values) > 0:
+		return ModeWrite
+	}
+
+	return ModeIdle
+}
+
+// countMode counts the cycle that just finished towards the node's mode.
+func (sn *stackNode) countMode() {
+	sn.modes[sn.mode()]++
+}
+
+func (sn *stackNode) getLeft() port {
+	return sn.left
+}
+
+func (sn *stackNode) getRight() port {
+	return sn.right
+}
+
+func (sn *stackNode) getUp() port {
+	return sn.up
+}
+
+func (sn *stackNode) getDown() port {
+	return sn.down
+}
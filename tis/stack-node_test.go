@@ -0,0 +1,106 @@
+package tis
+
+import (
+	"sort"
+	"testing"
+)
+
+// TestStackNodePorts tests that the stack node gives back the port on each of
+// its sides.
+func TestStackNodePorts(t *testing.T) {
+	up, down, left, right := newNodePort(), newNodePort(), newNodePort(), newNodePort()
+	sn := newStackNode("0-0", defaultStackCapacity, up, down, left, right)
+
+	if sn.getUp() != up || sn.getDown() != down || sn.getLeft() != left || sn.getRight() != right {
+		t.Error("expected the stack node to give back the port on each side")
+	}
+}
+
+// TestStackNodeCapacity tests that a stack node holds 15 numbers by default,
+// or as many as the config says, and that writers wait once it's full.
+func TestStackNodeCapacity(t *testing.T) {
+	testCases := []struct {
+		config   string
+		capacity int
+	}{
+		{
+			config:   `{"nodes": [["e", "s"]]}`,
+			capacity: 15},
+		{
+			config:   `{"nodes": [["e", "s"]], "stackCapacity": 3}`,
+			capacity: 3}}
+
+	for _, testCase := range testCases {
+		mach := newTestMachine(t, testCase.config, map[string]string{"0-0": "l: add 1\nmov acc right\njmp l\n"}, nil, nil)
+		mach.Run()
+
+		sn, _ := mach.Node("1-0")
+		if len(sn.Values) != testCase.capacity || sn.Values[len(sn.Values)-1] != Number(testCase.capacity) {
+			t.Errorf("expected the stack to hold 1 through %v, but got %v", testCase.capacity, sn.Values)
+		}
+		en, _ := mach.Node("0-0")
+		if en.Line != 2 || en.Waiting != "waiting to write RIGHT" {
+			t.Errorf("expected the writer to wait to write RIGHT on line 2, but got %+v", en)
+		}
+	}
+
+	if _, err := ParseConfig([]byte(`{"nodes": [["s"]], "stackCapacity": -1}`)); err == nil {
+		t.Error("expected a negative stack capacity to be rejected")
+	}
+}
+
+// TestStackNodeManyReadersAndWriters tests that a stack node with writers and
+// readers on every side at once passes along every number exactly once.
+func TestStackNodeManyReadersAndWriters(t *testing.T) {
+	// The nodes above and to the left of the stack write 1 through 10 and -10
+	// through -1, and the nodes to the right and below pass along what they
+	// read from it
+	right, bottom := &SliceOutput{}, &SliceOutput{}
+	mach := newTestMachine(t, `{
+		"nodes": [
+			["e", "e", "e"],
+			["e", "s", "e"],
+			["e", "e", "e"]],
+		"outputs": [
+			{"name": "RIGHT", "side": "right", "pos": 1},
+			{"name": "BOTTOM", "side": "bottom", "pos": 1}],
+		"stackCapacity": 3}`,
+		map[string]string{
+			"1-0": "mov 10 acc\nl: mov acc down\nsub 1\njnz l\nmov 0 up\n",
+			"0-1": "mov -10 acc\nl: mov acc right\nadd 1\njnz l\nmov 0 left\n",
+			"2-1": "mov left right\n",
+			"1-2": "mov up down\n"},
+		nil, map[string]OutputStream{"RIGHT": right, "BOTTOM": bottom})
+	mach.Run()
+
+	got := append(numbersToInts(right.Values), numbersToInts(bottom.Values)...)
+	sort.Ints(got)
+	var expected []int
+	for i := -10; i <= 10; i++ {
+		if i != 0 {
+			expected = append(expected, i)
+		}
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %v numbers to be read from the stack, but got %v", len(expected), got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("expected every number to be read exactly once, but got %v", got)
+		}
+	}
+
+	if sn, _ := mach.Node("1-1"); len(sn.Values) != 0 {
+		t.Error("expected the stack to be empty, but it holds", sn.Values)
+	}
+}
+
+// numbersToInts converts TIS-100 numbers to ints.
+func numbersToInts(ns []Number) []int {
+	ints := make([]int, len(ns))
+	for i, n := range ns {
+		ints[i] = int(n)
+	}
+
+	return ints
+}
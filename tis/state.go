@@ -0,0 +1,155 @@
+package tis
+
+import (
+	"errors"
+)
+
+// NodeKind is the kind of a node in the machine.
+type NodeKind int
+
+const (
+	ExecutionNode NodeKind = iota
+	StackNode
+)
+
+// NodeState is a snapshot of what a node is doing. Fields that don't apply to
+// the node's kind are left empty.
+type NodeState struct {
+	Name  string
+	Kind  NodeKind
+	X, Y  int
+	Mode  Mode       // What the node spent the last cycle doing
+	Modes ModeCycles // How many cycles the node has spent in each mode
+
+	// For execution nodes
+	Instructions int    // How many instructions the node has, or 0 if it's empty
+	Line         int    // The line of the current instruction, starting at 1
+	Instruction  string // The current instruction as it was parsed
+	Breakpoint   bool   // Whether the current instruction has a breakpoint
+	ACC, BAK     Number
+	Waiting      string // What the node is waiting on, like "waiting to read UP"
+	Executed     int    // How many instructions the node has finished
+
+	// For stack nodes
+	Values []Number // The stack, from bottom to top
+
+	// Numbers the node is offering on its sides that haven't been taken yet,
+	// keyed by UP, DOWN, LEFT or RIGHT
+	Sending map[string]Number
+}
+
+// Nodes returns the state of every node in the machine, from left to right
+// and top to bottom.
+func (m *Machine) Nodes() []NodeState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var states []NodeState
+	for y, row := range m.nodes {
+		for x, elem := range row {
+			states = append(states, nodeState(elem, x, y))
+		}
+	}
+
+	return states
+}
+
+// Node returns the state of the node with the given name, or false if there
+// is no such node.
+func (m *Machine) Node(name string) (NodeState, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for y, row := range m.nodes {
+		for x, elem := range row {
+			if nodeName(elem) == name {
+				return nodeState(elem, x, y), true
+			}
+		}
+	}
+
+	return NodeState{}, false
+}
+
+// SetBreakpoint sets or clears the breakpoint on the first instruction on or
+// after the given line of the named execution node. The line the breakpoint
+// ended up on is returned.
+func (m *Machine) SetBreakpoint(name string, line int, set bool) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, row := range m.nodes {
+		for _, elem := range row {
+			en, ok := elem.(*executionNode)
+			if !ok || en.name != name {
+				continue
+			}
+
+			for _, ins := range en.instructions {
+				if ins.base().line+1 >= line {
+					ins.base().breakpoint = set
+					return ins.base().line + 1, nil
+				}
+			}
+
+			return 0, errors.New("node " + name + " has no instructions on or after the given line")
+		}
+	}
+
+	return 0, errors.New("no execution node named '" + name + "'")
+}
+
+// nodeName returns the name of the given node.
+func nodeName(n node) string {
+	switch n := n.(type) {
+	case *executionNode:
+		return n.name
+	case *stackNode:
+		return n.name
+	}
+
+	return ""
+}
+
+// nodeState takes a snapshot of the given node, which is at the given
+// position in the machine.
+func nodeState(n node, x, y int) NodeState {
+	state := NodeState{
+		Name: nodeName(n),
+		X:    x,
+		Y:    y,
+		Mode: n.mode()}
+
+	switch n := n.(type) {
+	case *executionNode:
+		state.Kind = ExecutionNode
+		state.Instructions = len(n.instructions)
+		state.ACC = n.acc.value
+		state.BAK = n.bak.value
+		state.Waiting = n.waitStatus()
+		state.Executed = n.executed
+		state.Modes = n.modes
+		if len(n.instructions) > 0 {
+			ins := n.instructions[n.ip].base()
+			state.Line = ins.line + 1
+			state.Instruction = ins.text
+			state.Breakpoint = ins.breakpoint
+		}
+	case *stackNode:
+		state.Kind = StackNode
+		state.Values = append([]Number(nil), n.values...)
+		state.Modes = n.modes
+	}
+
+	sides := map[string]port{"UP": n.getUp(), "DOWN": n.getDown(), "LEFT": n.getLeft(), "RIGHT": n.getRight()}
+	for side, p := range sides {
+		if np, ok := p.(*nodePort); ok && np.offered.available() {
+			if state.Sending == nil {
+				state.Sending = make(map[string]Number)
+			}
+			state.Sending[side] = np.offered.n
+		}
+	}
+
+	return state
+}
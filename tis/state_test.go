@@ -0,0 +1,103 @@
+package tis
+
+import (
+	"testing"
+)
+
+// TestMachineState tests that the state of each node can be queried while the
+// machine runs.
+func TestMachineState(t *testing.T) {
+	mach := newTestMachine(t, `{"nodes": [["e", "s"]]}`,
+		map[string]string{"0-0": "mov 5 acc\nsav\nmov acc right\nmov 0 down\n"}, nil, nil)
+
+	for i := 0; i < 6; i++ {
+		mach.Step()
+	}
+
+	nodes := mach.Nodes()
+	if len(nodes) != 2 {
+		t.Fatal("expected 2 nodes but got", len(nodes))
+	}
+
+	en := nodes[0]
+	if en.Name != "0-0" || en.Kind != ExecutionNode || en.X != 0 || en.Y != 0 {
+		t.Error("expected execution node 0-0 at (0, 0) but got", en)
+	}
+	if en.ACC != 5 || en.BAK != 5 {
+		t.Error("expected ACC and BAK to be 5 but got", en.ACC, "and", en.BAK)
+	}
+	if en.Line != 4 || en.Instruction != "MOV 0 DOWN" || en.Waiting != "waiting to write DOWN" {
+		t.Errorf("expected node 0-0 to be waiting to write DOWN on line 4, but got %+v", en)
+	}
+	if n, ok := en.Sending["DOWN"]; !ok || n != 0 || len(en.Sending) != 1 {
+		t.Errorf("expected node 0-0 to be sending 0 DOWN, but got %v", en.Sending)
+	}
+
+	sn, ok := mach.Node("1-0")
+	if !ok {
+		t.Fatal("expected to find node 1-0")
+	}
+	if sn.Kind != StackNode || len(sn.Values) != 1 || sn.Values[0] != 5 {
+		t.Errorf("expected stack node 1-0 to hold [5], but got %+v", sn)
+	}
+	if n, ok := sn.Sending["LEFT"]; !ok || n != 5 {
+		t.Errorf("expected stack node 1-0 to offer 5 to the LEFT, but got %v", sn.Sending)
+	}
+	if _, ok := mach.Node("2-0"); ok {
+		t.Error("expected there to be no node 2-0")
+	}
+}
+
+// TestMachineModes tests that each node's mode is counted every cycle. A
+// number written to a stack node is taken a cycle after it's written, and the
+// stack then waits for it to be popped.
+func TestMachineModes(t *testing.T) {
+	mach := newTestMachine(t, `{"nodes": [["e", "e"], ["e", "s"]]}`,
+		map[string]string{
+			"1-0": "mov left acc\n",
+			"0-1": "mov 7 right\nnop\nnop\n"},
+		nil, nil)
+
+	for i := 0; i < 4; i++ {
+		mach.Step()
+	}
+
+	expected := []struct {
+		mode  Mode
+		modes ModeCycles
+	}{
+		{ModeIdle, ModeCycles{ModeIdle: 4}},
+		{ModeRead, ModeCycles{ModeRead: 4}},
+		{ModeRun, ModeCycles{ModeRun: 3, ModeWrite: 1}},
+		{ModeWrite, ModeCycles{ModeIdle: 1, ModeRun: 1, ModeWrite: 2}}}
+	for i, n := range mach.Nodes() {
+		if n.Mode != expected[i].mode || n.Modes != expected[i].modes {
+			t.Errorf("expected node %v to be in %v with %v, but got %v with %v",
+				n.Name, expected[i].mode, expected[i].modes, n.Mode, n.Modes)
+		}
+		if n.Modes.Total() != mach.Cycle() {
+			t.Errorf("expected node %v to count %v cycles, but got %v", n.Name, mach.Cycle(), n.Modes.Total())
+		}
+	}
+}
+
+// TestMachineSetBreakpoint tests that breakpoints land on the first
+// instruction on or after the given line.
+func TestMachineSetBreakpoint(t *testing.T) {
+	mach := newTestMachine(t, `{"nodes": [["e", "s"]]}`, map[string]string{"0-0": "nop\n\n# A comment\nnop\n"}, nil, nil)
+
+	if line, err := mach.SetBreakpoint("0-0", 2, true); err != nil || line != 4 {
+		t.Error("expected the breakpoint to be set on line 4, but got", line, err)
+	}
+	if _, err := mach.SetBreakpoint("0-0", 5, true); err == nil {
+		t.Error("expected no breakpoint to be set after the last instruction")
+	}
+	if _, err := mach.SetBreakpoint("1-0", 1, true); err == nil {
+		t.Error("expected no breakpoint to be set on a stack node")
+	}
+
+	mach.Step()
+	if n, _ := mach.Node("0-0"); !n.Breakpoint || n.Line != 4 {
+		t.Errorf("expected node 0-0 to be on the breakpoint on line 4, but got %+v", n)
+	}
+}
@@ -1,12 +1,12 @@
 // generated by stringer -type=tokenType; DO NOT EDIT
 
-package main
+package tis
 
 import "fmt"
 
-const _tokenType_name = "tokenNametokenLabeltokenNumber"
+const _tokenType_name = "tokenNametokenLabeltokenNumbertokenBreakpoint"
 
-var _tokenType_index = [...]uint8{0, 9, 19, 30}
+var _tokenType_index = [...]uint8{0, 9, 19, 30, 45}
 
 func (i tokenType) String() string {
 	i -= 1
@@ -0,0 +1,74 @@
+package tis
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// TraceRecord describes a single instruction run by an execution node. ACC and
+// BAK are their values after the instruction finished.
+type TraceRecord struct {
+	Cycle       int           `json:"cycle"`
+	Node        string        `json:"node"`
+	IP          int           `json:"ip"`   // The index of the instruction, starting at 0
+	Line        int           `json:"line"` // The line the instruction is on, starting at 1
+	Instruction string        `json:"instruction"`
+	ACC         Number        `json:"acc"`
+	BAK         Number        `json:"bak"`
+	Read        *PortTransfer `json:"read,omitempty"`  // The number read from a port, if any
+	Wrote       *PortTransfer `json:"wrote,omitempty"` // The number written to a port, if any
+}
+
+// PortTransfer is a number that moved through one of a node's ports. ANY and
+// LAST are resolved to the port the number actually moved through.
+type PortTransfer struct {
+	Port  string `json:"port"`
+	Value Number `json:"value"`
+}
+
+// tracer writes a trace record for every instruction the machine's execution
+// nodes finish. Nodes only call it when tracing is on, so a machine that isn't
+// being traced does no extra work.
+type tracer struct {
+	enc   *json.Encoder
+	cycle int // The cycle the machine is running
+}
+
+// record writes a record of the instruction at the given index, which the node
+// just finished, along with the numbers it read and wrote through its ports.
+func (tr *tracer) record(en *executionNode, ip int, read, wrote *PortTransfer) {
+	ins := en.instructions[ip].base()
+	tr.enc.Encode(TraceRecord{
+		Cycle:       tr.cycle,
+		Node:        en.name,
+		IP:          ip,
+		Line:        ins.line + 1,
+		Instruction: ins.text,
+		ACC:         en.acc.value,
+		BAK:         en.bak.value,
+		Read:        read,
+		Wrote:       wrote})
+}
+
+// Trace starts writing a JSON record of every instruction the machine's
+// execution nodes finish to w, one per line, in the order they finish. Any
+// error writing to w is ignored, so a buffered writer should be used and its
+// error checked when it's flushed. Tracing stops if w is nil.
+func (m *Machine) Trace(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tracer = nil
+	if w != nil {
+		m.tracer = &tracer{
+			enc: json.NewEncoder(w)}
+	}
+
+	for _, row := range m.nodes {
+		for _, elem := range row {
+			if en, ok := elem.(*executionNode); ok {
+				en.tracer = m.tracer
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package tis
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// TestMachineTrace tests that every finished instruction is traced with the
+// numbers that moved through the node's ports, and that ANY is traced as the
+// port it used. A write finishes when the cycle is committed, so it's traced
+// after the read that took its number, and a write to a console output is only
+// taken the cycle after it's published.
+func TestMachineTrace(t *testing.T) {
+	mach := newTestMachine(t, `{
+		"nodes": [["e", "e"]],
+		"outputs": [{"name": "OUT", "side": "bottom", "pos": 1}]}`,
+		map[string]string{
+			"0-0": "mov 5 right\nadd 1\nmov acc acc\n",
+			"1-0": "mov any acc\nsav\nmov acc down\n"},
+		nil, map[string]OutputStream{"OUT": &SliceOutput{}})
+
+	var buf bytes.Buffer
+	mach.Trace(&buf)
+	for i := 0; i < 5; i++ {
+		mach.Step()
+	}
+	mach.Trace(nil)
+	mach.Step()
+
+	var records []TraceRecord
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var record TraceRecord
+		if err := dec.Decode(&record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+
+	expected := []TraceRecord{
+		{Cycle: 2, Node: "1-0", IP: 0, Line: 1, Instruction: "MOV ANY ACC", ACC: 5, Read: &PortTransfer{"LEFT", 5}},
+		{Cycle: 2, Node: "0-0", IP: 0, Line: 1, Instruction: "MOV 5 RIGHT", Wrote: &PortTransfer{"RIGHT", 5}},
+		{Cycle: 3, Node: "0-0", IP: 1, Line: 2, Instruction: "ADD 1", ACC: 1},
+		{Cycle: 3, Node: "1-0", IP: 1, Line: 2, Instruction: "SAV", ACC: 5, BAK: 5},
+		{Cycle: 4, Node: "0-0", IP: 2, Line: 3, Instruction: "MOV ACC ACC", ACC: 1},
+		{Cycle: 5, Node: "1-0", IP: 2, Line: 3, Instruction: "MOV ACC DOWN", ACC: 5, BAK: 5, Wrote: &PortTransfer{"DOWN", 5}}}
+	if len(records) != len(expected) {
+		t.Fatalf("expected %v records, got %+v", len(expected), records)
+	}
+	for i := range expected {
+		got, _ := json.Marshal(records[i])
+		want, _ := json.Marshal(expected[i])
+		if !bytes.Equal(got, want) {
+			t.Errorf("expected record %v to be %s, got %s", i, want, got)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package tis
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// vcdWidth is how many bits a number is recorded with, which is enough for
+// every number from -999 to 999 in two's complement.
+const vcdWidth = 11
+
+// vcdSignal is a single value recorded in a Value Change Dump. Signals for
+// ports are pulses, which only have a value on the cycles a number moves
+// through them.
+type vcdSignal struct {
+	scope string // The node or console stream the signal belongs to, like "node_1-0"
+	name  string
+	id    string // The short code the signal is referred to by in the dump
+	pulse bool
+
+	sample func() (Number, bool) // Returns the signal's value, or false if it has none
+	value  string                // The value last written to the dump
+}
+
+// vcdRecorder writes a Value Change Dump of the machine's signals, with each
+// cycle as one unit of time.
+type vcdRecorder struct {
+	w       io.Writer
+	signals []*vcdSignal
+}
+
+// RecordVCD starts writing a Value Change Dump of the machine to w, which a
+// waveform viewer like GTKWave can show. Every number that moves out of a
+// node through one of its links, into the machine from a console input or out
+// to a console output is a signal, along with each execution node's ACC and
+// BAK. Each cycle is one unit of time. Any error writing to w is ignored, so a
+// buffered writer should be used and its error checked when it's flushed.
+// Recording stops if w is nil.
+func (m *Machine) RecordVCD(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.vcd = nil
+	if w == nil {
+		return
+	}
+
+	m.vcd = &vcdRecorder{
+		w:       w,
+		signals: m.vcdSignals()}
+	m.vcd.header()
+	m.vcd.sample(m.cycle)
+}
+
+// vcdSignals returns a signal for every value in the machine worth recording.
+func (m *Machine) vcdSignals() []*vcdSignal {
+	var signals []*vcdSignal
+	add := func(scope, name string, pulse bool, sample func() (Number, bool)) {
+		signals = append(signals, &vcdSignal{
+			scope:  scope,
+			name:   name,
+			id:     vcdID(len(signals)),
+			pulse:  pulse,
+			sample: sample})
+	}
+
+	// Only links with a node on both ends can carry numbers
+	owned := make(map[port]bool)
+	for _, row := range m.nodes {
+		for _, elem := range row {
+			for _, p := range []port{elem.getUp(), elem.getDown(), elem.getLeft(), elem.getRight()} {
+				owned[p] = true
+			}
+		}
+	}
+
+	for _, cin := range m.inputs {
+		cin := cin
+		read := cin.read
+		add("input_"+cin.name, "VALUE", true, func() (Number, bool) {
+			moved := cin.read != read
+			read = cin.read
+			return cin.last, moved
+		})
+	}
+
+	for _, row := range m.nodes {
+		for _, elem := range row {
+			name := "node_" + nodeName(elem)
+
+			if en, ok := elem.(*executionNode); ok {
+				add(name, "ACC", false, func() (Number, bool) { return en.acc.value, true })
+				add(name, "BAK", false, func() (Number, bool) { return en.bak.value, true })
+			}
+
+			sides := []struct {
+				name string
+				p    port
+			}{
+				{"UP", elem.getUp()},
+				{"DOWN", elem.getDown()},
+				{"LEFT", elem.getLeft()},
+				{"RIGHT", elem.getRight()}}
+			for _, side := range sides {
+				np, ok := side.p.(*nodePort)
+				if !ok || !owned[np.peer] {
+					continue
+				}
+
+				// A number moved out through the port if the transfer it
+				// offered was taken through it since the last cycle. A stack
+				// node offers its next number in the same cycle the last one
+				// is taken, so the taken one is looked up on the stack.
+				offered := func() *transfer { return np.offered }
+				if sn, ok := elem.(*stackNode); ok {
+					offered = func() *transfer { return sn.popped }
+				}
+				last := offered()
+				add(name, side.name, true, func() (Number, bool) {
+					t := offered()
+					if t == nil || t == last || !t.taken || t.from != np {
+						return 0, false
+					}
+					last = t
+					return t.n, true
+				})
+			}
+		}
+	}
+
+	for _, cout := range m.outputs {
+		cout := cout
+		written := cout.written
+		add("output_"+cout.name, "VALUE", true, func() (Number, bool) {
+			moved := cout.written != written
+			written = cout.written
+			return cout.last, moved
+		})
+	}
+
+	return signals
+}
+
+// header writes the dump's header, which declares every signal.
+func (vr *vcdRecorder) header() {
+	fmt.Fprintln(vr.w, "$version TISC-100 $end")
+	fmt.Fprintln(vr.w, "$comment Each unit of time is one cycle $end")
+	fmt.Fprintln(vr.w, "$timescale 1 ns $end")
+	fmt.Fprintln(vr.w, "$scope module tis $end")
+
+	scope := ""
+	for _, s := range vr.signals {
+		if s.scope != scope {
+			if scope != "" {
+				fmt.Fprintln(vr.w, "$upscope $end")
+			}
+			fmt.Fprintf(vr.w, "$scope module %v $end\n", vcdName(s.scope))
+			scope = s.scope
+		}
+		fmt.Fprintf(vr.w, "$var integer %v %v %v $end\n", vcdWidth, s.id, s.name)
+	}
+	if scope != "" {
+		fmt.Fprintln(vr.w, "$upscope $end")
+	}
+
+	fmt.Fprintln(vr.w, "$upscope $end")
+	fmt.Fprintln(vr.w, "$enddefinitions $end")
+}
+
+// sample writes the value of every signal that changed since the last sample,
+// at the given cycle. A pulse is written every time a number moves, even if
+// it's the same number as last time.
+func (vr *vcdRecorder) sample(cycle int) {
+	wroteTime := false
+	for _, s := range vr.signals {
+		value := "bx"
+		n, ok := s.sample()
+		if ok {
+			value = "b" + strconv.FormatUint(uint64(n)&(1<<vcdWidth-1), 2)
+		}
+		if value == s.value && !(s.pulse && ok) {
+			continue
+		}
+
+		if !wroteTime {
+			fmt.Fprintf(vr.w, "#%v\n", cycle)
+			wroteTime = true
+		}
+		fmt.Fprintf(vr.w, "%v %v\n", value, s.id)
+		s.value = value
+	}
+}
+
+// vcdName turns the given name into one that waveform viewers won't mistake
+// for a path, by replacing everything but letters, digits and underscores.
+func vcdName(name string) string {
+	return strings.Map(func(c rune) rune {
+		if c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c) {
+			return c
+		}
+		return '_'
+	}, name)
+}
+
+// vcdID returns the short code for the signal at the given index. Codes are
+// made of the printable ASCII characters.
+func vcdID(i int) string {
+	var id []byte
+	for {
+		id = append(id, byte('!'+i%94))
+		i /= 94
+		if i == 0 {
+			return string(id)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package tis
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestMachineRecordVCD tests that numbers moving through links and console
+// outputs are recorded as pulses, and ACC as a value that holds until it
+// changes.
+func TestMachineRecordVCD(t *testing.T) {
+	mach := newTestMachine(t, `{
+		"nodes": [["e", "e"]],
+		"outputs": [{"name": "OUT", "side": "bottom", "pos": 1}]}`,
+		map[string]string{
+			"0-0": "mov -1 right\nmov -1 right\n",
+			"1-0": "mov left acc\nmov acc down\n"},
+		nil, map[string]OutputStream{"OUT": &SliceOutput{}})
+
+	var buf bytes.Buffer
+	mach.RecordVCD(&buf)
+	for i := 0; i < 5; i++ {
+		mach.Step()
+	}
+	mach.RecordVCD(nil)
+
+	dump := buf.String()
+	for _, decl := range []string{
+		"$scope module node_0_0 $end",
+		"$var integer 11 ! ACC $end",
+		"$var integer 11 # RIGHT $end",
+		"$var integer 11 $ ACC $end",
+		"$scope module output_OUT $end",
+		"$var integer 11 ' VALUE $end",
+		"$enddefinitions $end"} {
+		if !strings.Contains(dump, decl) {
+			t.Errorf("expected the dump to contain %q, got:\n%v", decl, dump)
+		}
+	}
+
+	// 0-0 sends -1 on cycles 2 and 5, which 1-0 loads into ACC and sends out
+	// on cycle 4
+	changes := dump[strings.Index(dump, "#0"):]
+	if !strings.HasPrefix(changes, "#0\nb0 !\nb0 \"\nbx #\nb0 $\nb0 %\nbx &\nbx '\n#2") {
+		t.Errorf("expected every signal to start off, got:\n%v", changes)
+	}
+	for _, change := range []string{
+		"#2\nb11111111111 #\nb11111111111 $\n",
+		"#3\nbx #\n#4\nb11111111111 '\n",
+		"#5\nb11111111111 #\nbx '\n"} {
+		if !strings.Contains(changes, change) {
+			t.Errorf("expected the dump to contain %q, got:\n%v", change, changes)
+		}
+	}
+}
+
+// TestMachineRecordVCDStack tests that every number popped from a stack node
+// is recorded, even though the stack offers its next number in the same cycle.
+func TestMachineRecordVCDStack(t *testing.T) {
+	// 2-0 waits until all three numbers are on the stack before popping them
+	mach := newTestMachine(t, `{"nodes": [["e", "s", "e"]]}`,
+		map[string]string{
+			"0-0": "mov 7 right\nmov 8 right\nmov 9 right\nend: jmp end\n",
+			"2-0": "mov 8 acc\nwait: sub 1\njnz wait\nread: mov left acc\njmp read\n"},
+		nil, nil)
+
+	var buf bytes.Buffer
+	mach.RecordVCD(&buf)
+	for i := 0; i < 40; i++ {
+		mach.Step()
+	}
+	mach.RecordVCD(nil)
+
+	pulses := vcdPulses(buf.String(), "node_1_0", "RIGHT")
+	expected := []string{"b1001", "b1000", "b111"}
+	if strings.Join(pulses, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected the stack to send %v to the right, got %v", expected, pulses)
+	}
+}
+
+// vcdPulses returns every value the named signal in the given scope takes in
+// the dump, other than having no value.
+func vcdPulses(dump, scope, name string) []string {
+	id := ""
+	current := ""
+	for _, line := range strings.Split(dump, "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 4 && fields[0] == "$scope":
+			current = fields[2]
+		case len(fields) == 6 && fields[0] == "$var" && current == scope && fields[4] == name:
+			id = fields[3]
+		}
+	}
+
+	var pulses []string
+	for _, line := range strings.Split(dump[strings.Index(dump, "$enddefinitions"):], "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] == id && fields[0] != "bx" {
+			pulses = append(pulses, fields[0])
+		}
+	}
+
+	return pulses
+}
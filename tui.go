@@ -0,0 +1,406 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/velovix/TISC-100/tis"
+)
+
+// The sizes of what the TUI draws, in characters.
+const (
+	tuiTileWidth   = 22 // A node, including its border
+	tuiLinkWidth   = 7  // The gap between nodes side by side, where their links are drawn
+	tuiPanelWidth  = 20 // The console panel
+	tuiMaxCodeRows = 15 // The most lines of code a node holds in the game
+)
+
+const tuiHelp = "space run/pause   n step   f fast-forward   q quit"
+
+// tui is a full-screen view of a machine that looks like the game. Each node
+// is drawn as a tile in a grid, with the numbers moving between them on the
+// links in between, and the console streams in a panel on the side. The
+// machine is run, paused and stepped with the keyboard.
+type tui struct {
+	driver
+	config  tis.Config
+	code    map[string][]string // The lines of code in each node
+	streams []*panelStream
+
+	rows, cols int // The size of the terminal
+}
+
+// newTUI creates a TUI for a machine with the given config and code. The
+// console streams are wrapped so that the TUI can show what moves through
+// them, so the machine must be created with the streams afterwards.
+func newTUI(config tis.Config, code map[string]string, streams *consoleStreams) *tui {
+	return &tui{
+		config:  config,
+		code:    codeLines(code),
+		streams: watchStreams(config, streams),
+		rows:    24,
+		cols:    80}
+}
+
+// show draws the machine on the terminal and handles key presses until the
+// user quits.
+func (t *tui) show(term *terminal) {
+	keys := make(chan byte)
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			if _, err := os.Stdin.Read(buf); err != nil {
+				close(keys)
+				return
+			}
+			keys <- buf[0]
+		}
+	}()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	ticker := time.NewTicker(driveTick)
+	defer ticker.Stop()
+
+	var sized time.Time
+	redraw := true
+	for {
+		// The terminal can be resized at any time
+		if time.Since(sized) > time.Second {
+			rows, cols := term.size()
+			if rows != t.rows || cols != t.cols {
+				t.rows, t.cols = rows, cols
+				redraw = true
+			}
+			sized = time.Now()
+		}
+		if redraw {
+			fmt.Print(t.frame())
+			redraw = false
+		}
+
+		select {
+		case k, ok := <-keys:
+			if !ok || !t.key(k) {
+				return
+			}
+			redraw = true
+		case <-interrupt:
+			return
+		case <-ticker.C:
+			redraw = t.tick()
+		}
+	}
+}
+
+// key handles a key press. It returns false if the user wants to quit.
+func (t *tui) key(k byte) bool {
+	switch k {
+	case ' ':
+		if t.running {
+			t.pause()
+		} else {
+			t.run()
+		}
+	case 'n', 's':
+		t.stepOnce()
+	case 'f':
+		if t.fast {
+			t.pause()
+		} else {
+			t.fastForward()
+		}
+	case 'q', 'Q':
+		return false
+	}
+
+	return true
+}
+
+// frame draws the whole screen.
+func (t *tui) frame() string {
+	width, height := len(t.config.Nodes[0]), len(t.config.Nodes)
+
+	// Give each node as many lines of code as fit, up to the game's limit.
+	// Everything but the code takes up 4 rows in a tile, 6 rows around the
+	// grid and a row between each row of nodes.
+	codeRows := (t.rows-6-(height-1))/height - 4
+	if codeRows < 1 {
+		codeRows = 1
+	} else if codeRows > tuiMaxCodeRows {
+		codeRows = tuiMaxCodeRows
+	}
+	tileHeight := codeRows + 4
+
+	const gridTop = 2
+	gridHeight := height*tileHeight + height - 1
+	gridWidth := width*(tuiTileWidth+tuiLinkWidth) - tuiLinkWidth
+	panelX := gridWidth + 3
+	c := newCanvas(panelX+tuiPanelWidth, gridTop+gridHeight+4)
+
+	title := t.config.Name
+	if title == "" {
+		title = programName
+	}
+	c.text(0, 0, title, ansiBold)
+
+	// Look up nodes by where they are
+	tileLeft := func(x int) int { return x * (tuiTileWidth + tuiLinkWidth) }
+	tileTop := func(y int) int { return gridTop + y*(tileHeight+1) }
+	nodes := make(map[[2]int]tis.NodeState)
+	for _, n := range t.mach.Nodes() {
+		nodes[[2]int{n.X, n.Y}] = n
+		t.drawNode(c, n, tileLeft(n.X), tileTop(n.Y), codeRows)
+	}
+
+	// Draw the links between neighbours, with any numbers being sent over
+	// them
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			n := nodes[[2]int{x, y}]
+			left, top := tileLeft(x), tileTop(y)
+
+			if x+1 < width {
+				lx, mid := left+tuiTileWidth, top+tileHeight/2
+				drawLink(c, lx+1, mid-1, n.Sending, "RIGHT", "→", false)
+				drawLink(c, lx+1, mid, nodes[[2]int{x + 1, y}].Sending, "LEFT", "←", true)
+			}
+			if y+1 < height {
+				cx, row := left+tuiTileWidth/2, top+tileHeight
+				drawLink(c, cx-6, row, n.Sending, "DOWN", "↓", false)
+				drawLink(c, cx+1, row, nodes[[2]int{x, y + 1}].Sending, "UP", "↑", true)
+			}
+		}
+	}
+
+	// Mark where console streams plug into the top and bottom of the grid.
+	// The panel shows every stream, wherever it plugs in.
+	edges := func(streams []tis.StreamConfig, input bool) {
+		for _, sc := range streams {
+			if sc.Side != "top" && sc.Side != "bottom" {
+				continue
+			}
+
+			// Point the way numbers move
+			row, arrow := gridTop-1, "↓ "
+			if sc.Side == "bottom" {
+				row = gridTop + gridHeight
+			}
+			if (sc.Side == "top") != input {
+				arrow = "↑ "
+			}
+			c.text(tileLeft(sc.Pos)+tuiTileWidth/2-2, row, arrow+sc.Name, ansiBold)
+		}
+	}
+	edges(t.config.Inputs, true)
+	edges(t.config.Outputs, false)
+
+	t.drawPanel(c, panelX, gridTop, gridHeight)
+
+	// Say what the machine is doing and how to control it
+	c.text(0, gridTop+gridHeight+2, fmt.Sprintf("Cycle %v   %v", t.mach.Cycle(), t.state()), ansiBold)
+	c.text(0, gridTop+gridHeight+3, tuiHelp, ansiDim)
+
+	return ansiHome + c.render(t.rows, t.cols) + ansiClearScreen
+}
+
+// drawNode draws a node as a tile with the given top left corner.
+func (t *tui) drawNode(c *canvas, n tis.NodeState, left, top, codeRows int) {
+	inner := tuiTileWidth - 2
+	right := left + tuiTileWidth - 1
+	bottom := top + codeRows + 3
+
+	c.text(left, top, "┌"+strings.Repeat("─", inner)+"┐", ansiDim)
+	for y := top + 1; y < bottom; y++ {
+		c.text(left, y, "│", ansiDim)
+		c.text(right, y, "│", ansiDim)
+	}
+	c.text(left, bottom-2, "├"+strings.Repeat("─", inner)+"┤", ansiDim)
+	c.text(left, bottom, "└"+strings.Repeat("─", inner)+"┘", ansiDim)
+
+	mode := n.Mode.String()
+	c.text(left+1, top, n.Name, ansiBold)
+	c.text(right-len(mode), top, mode, ansiBold)
+
+	if n.Kind == tis.StackNode {
+		// Show the stack from the top down
+		for i := 0; i < codeRows && i < len(n.Values); i++ {
+			text := fmt.Sprint(n.Values[len(n.Values)-1-i])
+			if i == codeRows-1 && len(n.Values) > codeRows {
+				text = "…"
+			}
+			c.text(left+2, top+1+i, text, "")
+		}
+		c.text(left+2, top+codeRows+2, fmt.Sprintf("STACK  %v VALUES", len(n.Values)), "")
+		return
+	}
+
+	// Show a window of the code around the current line
+	lines := t.code[n.Name]
+	current := -1
+	if n.Instructions > 0 {
+		current = n.Line - 1
+	}
+	start := 0
+	if len(lines) > codeRows {
+		start = current - codeRows/2
+		if start > len(lines)-codeRows {
+			start = len(lines) - codeRows
+		}
+		if start < 0 {
+			start = 0
+		}
+	}
+	for i := 0; i < codeRows && start+i < len(lines); i++ {
+		text := " " + strings.Replace(lines[start+i], "\t", " ", -1)
+		if len([]rune(text)) > inner {
+			text = string([]rune(text)[:inner])
+		}
+		style := ""
+		if start+i == current {
+			text += strings.Repeat(" ", inner-len([]rune(text)))
+			style = ansiReverse
+		}
+		c.text(left+1, top+1+i, text, style)
+	}
+
+	c.text(left+2, top+codeRows+2, fmt.Sprintf("ACC %-5v BAK %v", n.ACC, n.BAK), "")
+}
+
+// drawPanel draws the console streams in a column with the given top left
+// corner and height. Each stream shows its most recent numbers.
+func (t *tui) drawPanel(c *canvas, left, top, height int) {
+	if len(t.streams) == 0 {
+		c.text(left, top, "No console streams", ansiDim)
+		return
+	}
+
+	section := height / len(t.streams)
+	if section < 2 {
+		section = 2
+	}
+	for i, ps := range t.streams {
+		y := top + i*section
+		kind := "out"
+		if ps.input {
+			kind = "in"
+		}
+		c.text(left, y, ps.name, ansiBold)
+		c.text(left+len([]rune(ps.name))+1, y, kind, ansiDim)
+
+		// Leave a blank row between sections
+		shown := section - 2
+		if i == len(t.streams)-1 {
+			shown = top + height - y - 1
+		}
+		values := ps.values
+		if len(values) > shown {
+			values = values[len(values)-shown:]
+		}
+		for j, n := range values {
+			c.text(left, y+1+j, fmt.Sprintf("%*v", tuiPanelWidth-2, n), "")
+		}
+	}
+}
+
+// drawLink draws one direction of a link at the given place, with the number
+// the node is sending that way if there is one. The arrow points in the
+// direction the number moves, and comes after the number unless arrowFirst is
+// set.
+func drawLink(c *canvas, x, y int, sending map[string]tis.Number, side, arrow string, arrowFirst bool) {
+	n, ok := sending[side]
+	if !ok {
+		if arrowFirst {
+			c.text(x, y, arrow, ansiDim)
+		} else {
+			c.text(x+4, y, arrow, ansiDim)
+		}
+		return
+	}
+
+	if arrowFirst {
+		c.text(x, y, fmt.Sprintf("%v%-4v", arrow, n), ansiBold)
+	} else {
+		c.text(x, y, fmt.Sprintf("%4v%v", n, arrow), ansiBold)
+	}
+}
+
+// cell is a single character on the screen and the escape code it's drawn
+// with.
+type cell struct {
+	r     rune
+	style string
+}
+
+// canvas is a screen's worth of characters that can be drawn on in any order
+// and then written to the terminal all at once.
+type canvas struct {
+	cells [][]cell
+}
+
+// newCanvas creates a blank canvas of the given size.
+func newCanvas(width, height int) *canvas {
+	c := &canvas{
+		cells: make([][]cell, height)}
+	for y := range c.cells {
+		c.cells[y] = make([]cell, width)
+		for x := range c.cells[y] {
+			c.cells[y][x] = cell{r: ' '}
+		}
+	}
+
+	return c
+}
+
+// text draws the text with its first character at the given place. Anything
+// that falls off the canvas isn't drawn.
+func (c *canvas) text(x, y int, text, style string) {
+	if y < 0 || y >= len(c.cells) {
+		return
+	}
+
+	row := c.cells[y]
+	for _, r := range text {
+		if x >= 0 && x < len(row) {
+			row[x] = cell{r: r, style: style}
+		}
+		x++
+	}
+}
+
+// render returns the canvas as text for a terminal, cut down to the given
+// number of rows and columns.
+func (c *canvas) render(rows, cols int) string {
+	var b strings.Builder
+	for y, row := range c.cells {
+		if y >= rows {
+			break
+		}
+		if y > 0 {
+			b.WriteString("\r\n")
+		}
+
+		style := ""
+		for x, cl := range row {
+			if x >= cols {
+				break
+			}
+			if cl.style != style {
+				b.WriteString(ansiReset + cl.style)
+				style = cl.style
+			}
+			b.WriteRune(cl.r)
+		}
+		if style != "" {
+			b.WriteString(ansiReset)
+		}
+		b.WriteString(ansiClearLine)
+	}
+
+	return b.String()
+}
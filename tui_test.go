@@ -0,0 +1,91 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/velovix/TISC-100/tis"
+)
+
+// stripANSI removes the escape codes from a frame drawn by the TUI.
+var stripANSI = regexp.MustCompile("\x1b\\[[0-9;?]*[A-Za-z]")
+
+// TestTUIFrame tests that the TUI draws each node with its mode, registers
+// and current line, the numbers waiting on links, and the console streams in
+// its panel.
+func TestTUIFrame(t *testing.T) {
+	config := parseTestConfig(t, `{
+		"name": "Frame",
+		"nodes": [["e", "e"]],
+		"inputs": [{"name": "IN", "side": "top", "pos": 0}],
+		"outputs": [{"name": "OUT", "side": "bottom", "pos": 1}]}`)
+	code := map[string]string{
+		"0-0": "mov up acc\nadd 1\nmov acc right\n",
+		"1-0": ""}
+	streams := consoleStreams{
+		inputs:  map[string]tis.InputStream{"IN": tis.NewSliceInput([]tis.Number{7})},
+		outputs: map[string]tis.OutputStream{"OUT": &tis.SliceOutput{}}}
+
+	view := newTUI(config, code, &streams)
+	view.mach = newTestMachine(t, config, code, streams)
+
+	// 0-0 reads 7 on the first cycle, adds 1 on the next and waits to send
+	// 8 to the idle node after that
+	for i := 0; i < 3; i++ {
+		view.mach.Step()
+	}
+
+	frame := stripANSI.ReplaceAllString(view.frame(), "")
+	lines := strings.Split(frame, "\r\n")
+	if len(lines) > view.rows {
+		t.Errorf("expected at most %v rows, got %v", view.rows, len(lines))
+	}
+	for _, want := range []string{
+		"Frame",
+		"↓ IN",
+		"↓ OUT",
+		"┌0-0─────────────WRTE┐",
+		"┌1-0─────────────IDLE┐",
+		"│ mov acc right      │",
+		"ACC 8     BAK 0",
+		"   8→",
+		"IN in",
+		"OUT out",
+		"Cycle 3   PAUSED"} {
+		if !strings.Contains(frame, want) {
+			t.Errorf("expected the frame to contain %q, got:\n%v", want, frame)
+		}
+	}
+
+	// The panel shows the numbers read from the input
+	if !regexp.MustCompile(`IN in[^\n]*\r\n[^\n]* 7 *\r\n`).MatchString(frame) {
+		t.Errorf("expected the panel to show 7 under IN, got:\n%v", frame)
+	}
+}
+
+// TestTUIStep tests that stepping a stopped machine keeps the reason it
+// stopped.
+func TestTUIStep(t *testing.T) {
+	config := parseTestConfig(t, `{"nodes": [["e"]]}`)
+	streams := consoleStreams{
+		inputs:  map[string]tis.InputStream{},
+		outputs: map[string]tis.OutputStream{}}
+	code := map[string]string{"0-0": "hcf\n"}
+
+	view := newTUI(config, code, &streams)
+	view.mach = newTestMachine(t, config, code, streams)
+
+	view.key('f')
+	for i := 0; i < 3 && view.step(); i++ {
+	}
+	if !strings.HasPrefix(view.stopped, "HALTED") {
+		t.Errorf("expected the machine to be halted, got %q", view.stopped)
+	}
+	if view.fast || view.running {
+		t.Errorf("expected the machine to stop fast-forwarding when it halted")
+	}
+	if view.key(' '); view.running {
+		t.Errorf("expected a halted machine not to run again")
+	}
+}
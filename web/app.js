@@ -0,0 +1,185 @@
+"use strict";
+
+// The browser view of a machine served by TISC-100 serve. The server sends
+// the whole state of the machine every time it changes, and the grid is
+// redrawn from it.
+
+const grid = document.getElementById("grid");
+const streams = document.getElementById("streams");
+const runButton = document.getElementById("run");
+
+let current = null;
+
+// el creates an element with the given class and text.
+function el(tag, className, text) {
+	const e = document.createElement(tag);
+	if (className) {
+		e.className = className;
+	}
+	if (text !== undefined) {
+		e.textContent = text;
+	}
+	return e;
+}
+
+// place puts an element in the given column and row of the grid, counting
+// from 1.
+function place(e, column, row) {
+	e.style.gridColumn = column;
+	e.style.gridRow = row;
+	grid.appendChild(e);
+}
+
+// link draws one direction of a link, with the number the node is sending
+// that way if there is one.
+function link(node, side, arrow) {
+	if (!node.sending || node.sending[side] === undefined) {
+		return el("span", "", arrow);
+	}
+	return el("span", "value", arrow + " " + node.sending[side]);
+}
+
+function renderNode(node) {
+	const tile = el("div", "node");
+	if (node.mode === "READ" || node.mode === "WRTE") {
+		tile.classList.add("waiting");
+	}
+
+	const title = el("div", "title");
+	title.appendChild(el("span", "name", node.name));
+	title.appendChild(el("span", "mode", node.mode));
+	tile.appendChild(title);
+
+	const code = el("div", "code");
+	if (node.stack) {
+		// Show the stack from the top down
+		const values = (node.values || []).slice().reverse();
+		for (const value of values) {
+			code.appendChild(el("div", "", String(value)));
+		}
+		tile.appendChild(code);
+		tile.appendChild(el("div", "registers", "STACK " + values.length + " VALUES"));
+		return tile;
+	}
+
+	const lines = node.code || [];
+	if (lines.length === 0) {
+		tile.classList.add("empty");
+	}
+	lines.forEach((line, i) => {
+		const row = el("div", i + 1 === node.line ? "current" : "", line || " ");
+		code.appendChild(row);
+	});
+	tile.appendChild(code);
+
+	const registers = el("div", "registers", "ACC " + node.acc + "  BAK " + node.bak);
+	if (node.waiting) {
+		registers.title = node.waiting;
+	}
+	tile.appendChild(registers);
+	return tile;
+}
+
+function render(state) {
+	current = state;
+	document.getElementById("name").textContent = state.name;
+	document.title = state.name + " - TISC-100";
+	document.getElementById("cycle").textContent = "Cycle " + state.cycle;
+	document.getElementById("state").textContent = state.state;
+	runButton.textContent = state.state === "RUNNING" || state.state === "FAST-FORWARD" ? "Pause" : "Run";
+
+	// Nodes are in odd columns and even rows, with links between them. The
+	// first and last rows hold the console streams on the top and bottom.
+	grid.replaceChildren();
+	grid.style.gridTemplateColumns = "repeat(" + (2 * state.width - 1) + ", auto)";
+
+	const nodes = {};
+	for (const node of state.nodes) {
+		nodes[node.x + "," + node.y] = node;
+		place(renderNode(node), 2 * node.x + 1, 2 * node.y + 2);
+	}
+
+	for (const node of state.nodes) {
+		const right = nodes[(node.x + 1) + "," + node.y];
+		if (right) {
+			const e = el("div", "link");
+			e.appendChild(link(node, "RIGHT", "→"));
+			e.appendChild(document.createElement("br"));
+			e.appendChild(link(right, "LEFT", "←"));
+			place(e, 2 * node.x + 2, 2 * node.y + 2);
+		}
+
+		const below = nodes[node.x + "," + (node.y + 1)];
+		if (below) {
+			const e = el("div", "link");
+			e.appendChild(link(node, "DOWN", "↓"));
+			e.appendChild(document.createTextNode("   "));
+			e.appendChild(link(below, "UP", "↑"));
+			place(e, 2 * node.x + 1, 2 * node.y + 3);
+		}
+	}
+
+	streams.replaceChildren();
+	for (const stream of state.streams || []) {
+		if (stream.side === "top" || stream.side === "bottom") {
+			const arrow = (stream.side === "top") === stream.input ? "↓ " : "↑ ";
+			const row = stream.side === "top" ? 1 : 2 * state.height + 1;
+			place(el("div", "edge", arrow + stream.name), 2 * stream.pos + 1, row);
+		}
+
+		const panel = el("section", "stream");
+		const heading = el("h2", "", stream.name + " ");
+		heading.appendChild(el("span", "kind", stream.input ? "in" : "out"));
+		panel.appendChild(heading);
+		const list = el("ol");
+		for (const value of stream.values) {
+			list.appendChild(el("li", "", String(value)));
+		}
+		panel.appendChild(list);
+		streams.appendChild(panel);
+		list.scrollTop = list.scrollHeight;
+	}
+}
+
+function control(action) {
+	fetch("control", {
+		method: "POST",
+		body: new URLSearchParams({action: action})
+	});
+}
+
+function toggleRun() {
+	const running = current && (current.state === "RUNNING" || current.state === "FAST-FORWARD");
+	control(running ? "pause" : "run");
+}
+
+runButton.addEventListener("click", toggleRun);
+document.getElementById("step").addEventListener("click", () => control("step"));
+document.getElementById("fast").addEventListener("click", () => control("fast"));
+document.addEventListener("keydown", (event) => {
+	if (event.target.tagName === "BUTTON" && event.key === " ") {
+		// Let the button handle it
+		return;
+	}
+	switch (event.key) {
+	case " ":
+		toggleRun();
+		break;
+	case "n":
+	case "s":
+		control("step");
+		break;
+	case "f":
+		control("fast");
+		break;
+	default:
+		return;
+	}
+	event.preventDefault();
+});
+
+const events = new EventSource("events");
+events.onmessage = (event) => render(JSON.parse(event.data));
+events.onerror = () => {
+	document.getElementById("state").textContent = "DISCONNECTED";
+};